@@ -1,24 +1,45 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"flag"
+	"fmt"
 	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/holoplot/go-sap/pkg/sap"
+	"github.com/holoplot/go-sap/pkg/sdp"
 	"github.com/mattn/go-colorable"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 func main() {
-	destFlag := flag.String("dest", "239.255.255.255", "Multicast group to listen to")
-	originFlag := flag.String("origin", "192.168.1.100", "Origin to use in sent packets")
+	destFlag := flag.String("dest", "239.255.255.255", "Multicast group to send to, IPv6 groups may carry a zone (ff02::2:7ffe%eth0)")
+	originFlag := flag.String("origin", "", "Origin to use in sent packets (empty to use the address of the outgoing interface)")
+	checkOriginFlag := flag.Bool("check-origin", false, "Refuse to use an origin that is not a local address")
 	timeoutFlag := flag.Int("timeout", 0, "Timeout in seconds (0 for disable)")
-	sdpFlag := flag.String("sdp", "sdp.txt", "SDP file to use as payload")
+	sdpFlag := flag.String("sdp", "sdp.txt", "SDP files to use as payload, separated by commas")
+	adaptiveFlag := flag.Bool("adaptive", false, "Adapt the interval to the announcements heard on the group")
+	ifaceFlag := flag.String("iface", "", "Interface name to send announcements on")
+	ttlFlag := flag.Int("ttl", 0, "Multicast TTL or hop limit (0 for the default of the group's scope)")
+	noLoopbackFlag := flag.Bool("no-loopback", false, "Do not deliver announcements to listeners on this host")
+	strictFlag := flag.Bool("strict", false, "Refuse SDP files that fail validation instead of warning")
+	aes67Flag := flag.Bool("aes67", false, "Validate audio streams against AES67")
+	sessionTimingFlag := flag.Bool("session-timing", false, "Announce sessions only until the stop time of their t= lines")
+	aheadFlag := flag.Duration("ahead", time.Hour, "How long before the start time of a session to announce it (with -session-timing)")
+	certFlag := flag.String("cert", "", "PEM file with the certificate (and intermediates) to sign packets with")
+	keyFlag := flag.String("key", "", "PEM file with the private key to sign packets with (with -cert)")
+	encryptFlag := flag.String("encrypt", "", "AES key to encrypt announcements with, as <key-id>:<secret> in hex")
 	flag.Parse()
 
 	consoleWriter := zerolog.ConsoleWriter{
@@ -27,35 +48,210 @@ func main() {
 
 	log.Logger = log.Output(consoleWriter)
 
-	ip := net.ParseIP(*destFlag)
-
-	b, err := os.ReadFile(*sdpFlag)
+	ip, ifi, err := sap.ParseGroup(*destFlag)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to read SDP file")
+		log.Fatal().Err(err).Msg("Invalid multicast group")
 	}
 
-	p := &sap.Packet{
-		Type:        sap.MessageTypeAnnouncement,
-		IDHash:      0x2342,
-		Origin:      net.ParseIP(*originFlag),
-		PayloadType: sap.SDPPayloadType,
-		Payload:     b,
+	if *ifaceFlag != "" {
+		ifi, err = net.InterfaceByName(*ifaceFlag)
+		if err != nil {
+			log.Fatal().Err(err).Msg("No such interface")
+		}
 	}
 
 	ctx := context.Background()
 
 	if *timeoutFlag > 0 {
-		ctx, _ = context.WithDeadline(ctx, time.Now().Add(time.Duration(*timeoutFlag)*time.Second))
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithDeadline(ctx, time.Now().Add(time.Duration(*timeoutFlag)*time.Second))
+		defer cancel()
+	}
+
+	opts := []sap.Option{
+		sap.WithOriginFromInterface(),
+		sap.WithPayloadIDHash(),
+		sap.WithErrorHandler(func(group net.IP, err error) {
+			log.Error().Err(err).IPAddr("dest", group).Msg("Failed to send announcement")
+		}),
+	}
+
+	if *strictFlag {
+		opts = append(opts, sap.WithValidation(nil))
+	} else {
+		opts = append(opts, sap.WithValidation(func(p *sap.Packet, err error) {
+			log.Warn().Err(err).IPAddr("origin", p.Origin).Msg("Announcing invalid payload")
+		}))
+	}
+
+	if *aes67Flag {
+		opts = append(opts, sap.WithAES67Validation())
+	}
+
+	if *sessionTimingFlag {
+		opts = append(opts, sap.WithSessionTiming(*aheadFlag))
+	}
+
+	if *checkOriginFlag {
+		opts = append(opts, sap.WithOriginValidation())
+	}
+
+	if *adaptiveFlag {
+		opts = append(opts, sap.WithAdaptiveInterval())
+	}
+
+	if ifi != nil {
+		opts = append(opts, sap.WithInterface(ifi))
+	}
+
+	if *ttlFlag > 0 {
+		opts = append(opts, sap.WithTTL(*ttlFlag))
+	}
+
+	if *noLoopbackFlag {
+		opts = append(opts, sap.WithLoopback(false))
+	}
+
+	if *certFlag != "" {
+		authenticator, err := loadCMSAuthenticator(*certFlag, *keyFlag)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load signing certificate")
+		}
+
+		opts = append(opts, sap.WithAuthenticator(authenticator))
+	}
+
+	var keyID uint32
+
+	if *encryptFlag != "" {
+		var key sap.Key
+
+		keyID, key, err = sap.ParseAESKey(*encryptFlag)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid encryption key")
+		}
+
+		opts = append(opts, sap.WithKeyring(sap.Keys{keyID: key}))
+	}
+
+	a := sap.NewAnnouncer(opts...)
+
+	announcements := make(map[string]*sap.Announcement)
+
+	for _, filename := range strings.Split(*sdpFlag, ",") {
+		b, err := readSDP(filename)
+		if err != nil {
+			log.Fatal().Err(err).Str("filename", filename).Msg("Failed to read SDP file")
+		}
+
+		p := &sap.Packet{
+			Type:        sap.MessageTypeAnnouncement,
+			Origin:      net.ParseIP(*originFlag),
+			PayloadType: sap.SDPPayloadType,
+			Payload:     b,
+			Encrypted:   *encryptFlag != "",
+			KeyID:       keyID,
+		}
+
+		an, err := a.Add(ip, p)
+		if err != nil {
+			log.Fatal().Err(err).Str("filename", filename).Msg("Failed to add announcement")
+		}
+
+		log.Info().
+			IPAddr("dest", ip).
+			IPAddr("origin", an.Packet().Origin).
+			Str("filename", filename).
+			Str("payload-type", p.PayloadType).
+			Str("id-hash", fmt.Sprintf("%04x", an.Packet().IDHash)).
+			Msg("Added announcement")
+
+		announcements[filename] = an
 	}
 
+	go reloadOnHangup(announcements)
+
 	log.Info().
-		IPAddr("dest", ip).
-		IPAddr("origin", p.Origin).
 		Int("timeout", *timeoutFlag).
-		Str("payload-type", p.PayloadType).
 		Msg("Sending announcements periodically")
 
-	if err := sap.AnnouncePeriodically(ctx, ip, p); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+	if err := a.Run(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
 		log.Fatal().Err(err).Msg("Failed to announce periodically")
 	}
 }
+
+func loadCMSAuthenticator(certFile, keyFile string) (*sap.CMSAuthenticator, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", pair.PrivateKey)
+	}
+
+	a := &sap.CMSAuthenticator{
+		Certificate: pair.Leaf,
+		Key:         key,
+	}
+
+	for _, der := range pair.Certificate[1:] {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+
+		a.Intermediates = append(a.Intermediates, cert)
+	}
+
+	return a, nil
+}
+
+// readSDP parses an SDP file and serializes it again, so hand-written files
+// with LF line endings or odd field order go out well-formed.
+func readSDP(filename string) ([]byte, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := sdp.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Marshal()
+}
+
+// reloadOnHangup re-reads the SDP files on SIGHUP and updates the running
+// announcements whose payload changed.
+func reloadOnHangup(announcements map[string]*sap.Announcement) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	for range c {
+		for filename, an := range announcements {
+			b, err := readSDP(filename)
+			if err != nil {
+				log.Error().Err(err).Str("filename", filename).Msg("Failed to read SDP file")
+				continue
+			}
+
+			if bytes.Equal(b, an.Packet().Payload) {
+				continue
+			}
+
+			if err := an.Update(b); err != nil {
+				log.Error().Err(err).Str("filename", filename).Msg("Failed to update announcement")
+				continue
+			}
+
+			log.Info().
+				Str("filename", filename).
+				Str("id-hash", fmt.Sprintf("%04x", an.Packet().IDHash)).
+				Msg("Updated announcement")
+		}
+	}
+}
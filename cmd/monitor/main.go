@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"os"
+	"strings"
 
 	"github.com/holoplot/go-sap/pkg/sap"
 	"github.com/mattn/go-colorable"
@@ -12,10 +16,18 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+type datagramReader interface {
+	ReadDatagram() (*sap.Datagram, error)
+}
+
 func main() {
-	ipFlag := flag.String("dest", "239.255.255.255", "Multicast group to listen to")
-	ifaceFlag := flag.String("iface", "", "Interface name to use")
+	ipFlag := flag.String("dest", "239.255.255.255", "Multicast groups to listen to, separated by commas. IPv6 groups may carry a zone (ff02::2:7ffe%eth0)")
+	ifaceFlag := flag.String("iface", "", "Interface names to use, separated by commas")
+	allIfacesFlag := flag.Bool("all-ifaces", false, "Listen on all multicast capable interfaces")
 	writeFileFlag := flag.Bool("write-file", false, "Write packets to files in the current directory")
+	decryptFlag := flag.String("decrypt", "", "AES keys to decrypt packets with, as <key-id>:<secret> in hex, separated by commas")
+	authorizeDeletionsFlag := flag.Bool("authorize-deletions", false, "Only honor deletions and updates from the source (and signer) of the first announcement")
+	trustFlag := flag.String("trust", "", "PEM file with the CA certificates to verify signed packets against (drops packets that fail)")
 	flag.Parse()
 
 	consoleWriter := zerolog.ConsoleWriter{
@@ -24,47 +36,174 @@ func main() {
 
 	log.Logger = log.Output(consoleWriter)
 
-	var ifi *net.Interface
+	var groups []net.IP
+	var ifis []*net.Interface
+
+	for _, s := range strings.Split(*ipFlag, ",") {
+		ip, ifi, err := sap.ParseGroup(s)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid multicast group")
+		}
+
+		groups = append(groups, ip)
+
+		if ifi != nil {
+			ifis = append(ifis, ifi)
+		}
+	}
 
 	if *ifaceFlag != "" {
-		var err error
+		for _, name := range strings.Split(*ifaceFlag, ",") {
+			ifi, err := net.InterfaceByName(name)
+			if err != nil {
+				log.Fatal().Err(err).Msg("No such interface")
+			}
+
+			ifis = append(ifis, ifi)
+		}
+	}
 
-		ifi, err = net.InterfaceByName(*ifaceFlag)
+	var opts []sap.ListenerOption
+
+	if *trustFlag != "" {
+		b, err := os.ReadFile(*trustFlag)
 		if err != nil {
-			log.Fatal().Err(err).Msg("No such interface")
+			log.Fatal().Err(err).Msg("Failed to read trust store")
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(b) {
+			log.Fatal().Str("filename", *trustFlag).Msg("No certificates in trust store")
 		}
+
+		opts = append(opts, sap.WithVerification(&sap.CMSAuthenticator{Roots: roots}))
 	}
 
-	ip := net.ParseIP(*ipFlag)
+	keys := sap.Keys{}
 
-	l, err := sap.NewListener(ip, ifi)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to listen")
+	if *decryptFlag != "" {
+		for _, s := range strings.Split(*decryptFlag, ",") {
+			keyID, key, err := sap.ParseAESKey(s)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Invalid decryption key")
+			}
+
+			keys[keyID] = key
+		}
+	}
+
+	var l datagramReader
+
+	if len(groups) == 1 && len(ifis) <= 1 && !*allIfacesFlag {
+		var ifi *net.Interface
+
+		if len(ifis) == 1 {
+			ifi = ifis[0]
+		}
+
+		var err error
+
+		l, err = sap.NewListener(groups[0], ifi, opts...)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to listen")
+		}
+	} else {
+		if *allIfacesFlag {
+			ifis = nil
+		}
+
+		var err error
+
+		l, err = sap.NewMultiListener(groups, ifis, opts...)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to listen")
+		}
 	}
 
 	log.Info().Msg("Listening for packets")
 
+	dirOpts := []sap.DirectoryOption{sap.WithDecryptionKeyring(keys)}
+
+	if *authorizeDeletionsFlag {
+		dirOpts = append(dirOpts, sap.WithDeletionAuthorization())
+	}
+
+	d := sap.NewDirectory(dirOpts...)
+
+	ctx := context.Background()
+
+	go d.Run(ctx)
+
+	go func() {
+		for e := range d.Subscribe(ctx) {
+			if e.Type == sap.EventTypeDeletionRejected || e.Type == sap.EventTypeAnnouncementRejected {
+				source := ""
+				if e.Datagram != nil {
+					source = e.Datagram.Source.String()
+				}
+
+				log.Warn().
+					Err(e.Err).
+					IPAddr("origin", e.Session.Packet.Origin).
+					Str("id-hash", fmt.Sprintf("%04x", e.Session.Packet.IDHash)).
+					Str("event", e.Type.String()).
+					Str("source", source).
+					Msg("Packet rejected")
+
+				continue
+			}
+
+			log.Info().
+				Str("event", e.Type.String()).
+				IPAddr("origin", e.Session.Packet.Origin).
+				Str("id-hash", fmt.Sprintf("%04x", e.Session.Packet.IDHash)).
+				Time("first-seen", e.Session.FirstSeen).
+				Dur("interval", e.Session.Interval).
+				Msg("Session changed")
+		}
+	}()
+
 	for {
-		b, err := l.ReadPacketRaw()
+		dg, err := l.ReadDatagram()
+		if authErr := (*sap.AuthenticationError)(nil); errors.As(err, &authErr) {
+			log.Warn().Err(err).Str("source", dg.Source.String()).Msg("Dropping packet without valid signature")
+
+			continue
+		}
+
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to read raw packet")
 
 			return
 		}
 
-		p, err := sap.DecodePacket(b)
+		p, err := d.HandleDatagram(dg)
 		if err != nil {
 			log.Error().Err(err).Stack().Msg("Failed to decode packet")
 
 			continue
 		}
 
+		sessionName := ""
+
+		if s, err := p.SDP(); err == nil {
+			sessionName = s.Name
+		} else if p.Type == sap.MessageTypeAnnouncement && p.PayloadType != "" {
+			log.Warn().Err(err).Msg("Failed to parse SDP payload")
+		}
+
 		log.Info().
 			IPAddr("origin", p.Origin).
+			Str("session-name", sessionName).
+			Str("source", dg.Source.String()).
+			Int("ifindex", dg.IfIndex).
+			IPAddr("group", dg.Group).
 			Bool("compressed", p.Compressed).
+			Bool("encrypted", p.Encrypted).
 			Bool("is-announcement", p.Type == sap.MessageTypeAnnouncement).
 			Str("id-hash", fmt.Sprintf("%04x", p.IDHash)).
 			Str("payload-type", p.PayloadType).
+			Str("signer", dg.Signer).
 			Msg("Packet received")
 
 		if *writeFileFlag {
@@ -3,12 +3,17 @@ module github.com/holoplot/go-sap
 go 1.23
 
 require (
+	github.com/ProtonMail/go-crypto v1.3.0
+	github.com/digitorus/pkcs7 v0.0.0-20250730155240-ffadbf3f398c
 	github.com/mattn/go-colorable v0.1.14
 	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.34.0
+	golang.org/x/net v0.34.0
+	golang.org/x/sys v0.30.0
 )
 
 require (
+	github.com/cloudflare/circl v1.6.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/crypto v0.33.0 // indirect
 )
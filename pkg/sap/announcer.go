@@ -0,0 +1,437 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	idleWakeupInterval = time.Hour
+)
+
+var ErrAnnouncerClosed = errors.New("announcer closed")
+var ErrUnknownAnnouncement = errors.New("unknown announcement")
+var ErrSessionEnded = errors.New("session has ended")
+
+type Announcement struct {
+	announcer *Announcer
+	group     *announcerGroup
+	packet    Packet
+	raw       []byte
+	next      time.Time
+	end       time.Time
+}
+
+// Packet returns the packet as currently announced, which Update may replace
+// concurrently.
+func (an *Announcement) Packet() Packet {
+	an.announcer.mutex.Lock()
+	defer an.announcer.mutex.Unlock()
+
+	return an.packet
+}
+
+func (an *Announcement) Group() net.IP {
+	return an.group.ip
+}
+
+// Update replaces the payload of a running announcement. As RFC 2974 requires
+// for modified sessions, a new message ID hash is assigned, or derived from the
+// new payload with WithPayloadIDHash. No deletion packet is sent for the old
+// one. The new announcement is sent immediately, unless WithSessionTiming
+// moves its start into the future, in which case it waits until then. Payloads
+// of sessions that have already ended are refused with ErrSessionEnded.
+func (an *Announcement) Update(payload []byte) error {
+	a := an.announcer
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.closed {
+		return ErrAnnouncerClosed
+	}
+
+	if !an.group.contains(an) {
+		return ErrUnknownAnnouncement
+	}
+
+	packet := an.packet
+	packet.Payload = payload
+
+	if err := a.config.validate(an.group.ip, &packet); err != nil {
+		return fmt.Errorf("validating payload: %w", err)
+	}
+
+	now := time.Now()
+
+	start, end := a.config.sessionWindow(&packet)
+	if !end.IsZero() && now.After(end) {
+		return ErrSessionEnded
+	}
+
+	if a.config.payloadIDHash {
+		if err := a.config.resolveIDHash(&packet, a.idHashTaken(&packet, an)); err != nil {
+			return err
+		}
+	} else {
+		packet.IDHash = nextIDHash(packet.IDHash)
+	}
+
+	raw, err := a.config.encode(&packet)
+	if err != nil {
+		return fmt.Errorf("encoding announcement package: %w", err)
+	}
+
+	an.packet = packet
+	an.raw = raw
+	an.end = end
+
+	a.wakeup()
+
+	if start.After(now) {
+		an.next = start
+
+		return nil
+	}
+
+	interval := an.group.interval(a.config.minInterval)
+	an.next = now.Add(interval + announcementOffset(interval))
+
+	if _, err := an.group.conn.Write(raw); err != nil {
+		return fmt.Errorf("sending announcement package: %w", err)
+	}
+
+	return nil
+}
+
+// nextIDHash returns a random message ID hash that differs from old. Zero is
+// avoided, as earlier versions of SAP use it to mean that the hash should be
+// ignored.
+func nextIDHash(old uint16) uint16 {
+	for {
+		h := uint16(rand.Intn(0x10000))
+
+		if h != 0 && h != old {
+			return h
+		}
+	}
+}
+
+type announcerGroup struct {
+	ip            net.IP
+	conn          *groupConn
+	traffic       *trafficMonitor
+	announcements []*Announcement
+}
+
+// RFC 2974, section 3.1:
+// The interval is computed from the total size of all announcements sent to
+// the group, so the aggregate rate stays below the bandwidth limit.
+func (g *announcerGroup) interval(minInterval time.Duration) time.Duration {
+	size := 0
+	own := make(map[string]bool)
+
+	for _, an := range g.announcements {
+		size += len(an.raw)
+		own[an.packet.UniqueID()] = true
+	}
+
+	if g.traffic != nil {
+		size += g.traffic.size(func(id string) bool {
+			return own[id]
+		})
+	}
+
+	return announcementInterval(size, minInterval)
+}
+
+func (g *announcerGroup) close() {
+	g.conn.Close()
+
+	if g.traffic != nil {
+		g.traffic.close()
+	}
+}
+
+func (g *announcerGroup) contains(an *Announcement) bool {
+	return slices.Contains(g.announcements, an)
+}
+
+func (g *announcerGroup) remove(an *Announcement) bool {
+	for i, other := range g.announcements {
+		if other == an {
+			g.announcements = append(g.announcements[:i], g.announcements[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *announcerGroup) sendDeletion(an *Announcement) error {
+	return an.announcer.config.sendDeletion(g.conn, an.packet)
+}
+
+// Announcer announces any number of sessions on any number of groups. It owns
+// one socket per group and schedules all sessions of a group together.
+type Announcer struct {
+	config config
+	mutex  sync.Mutex
+	groups map[string]*announcerGroup
+	wake   chan struct{}
+	closed bool
+}
+
+func NewAnnouncer(opts ...Option) *Announcer {
+	return &Announcer{
+		config: newConfig(opts),
+		groups: make(map[string]*announcerGroup),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+func (a *Announcer) wakeup() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Add starts announcing p on the multicast group ip. The first announcement is
+// sent by Run as soon as possible, or ahead of the start time of the session
+// with WithSessionTiming.
+func (a *Announcer) Add(ip net.IP, p *Packet) (*Announcement, error) {
+	packet := *p
+	packet.Type = MessageTypeAnnouncement
+
+	if err := a.config.resolveOrigin(ip, &packet); err != nil {
+		return nil, fmt.Errorf("resolving origin: %w", err)
+	}
+
+	if err := a.config.validate(ip, &packet); err != nil {
+		return nil, fmt.Errorf("validating payload: %w", err)
+	}
+
+	now := time.Now()
+
+	start, end := a.config.sessionWindow(&packet)
+	if !end.IsZero() && now.After(end) {
+		return nil, ErrSessionEnded
+	}
+
+	if start.Before(now) {
+		start = now
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.closed {
+		return nil, ErrAnnouncerClosed
+	}
+
+	if err := a.config.resolveIDHash(&packet, a.idHashTaken(&packet, nil)); err != nil {
+		return nil, err
+	}
+
+	raw, err := a.config.encode(&packet)
+	if err != nil {
+		return nil, fmt.Errorf("encoding announcement package: %w", err)
+	}
+
+	g, ok := a.groups[ip.String()]
+	if !ok {
+		conn, err := dialGroup(ip, &a.config)
+		if err != nil {
+			return nil, err
+		}
+
+		g = &announcerGroup{
+			ip:   ip,
+			conn: conn,
+		}
+
+		if a.config.adaptiveInterval {
+			g.traffic, err = newTrafficMonitor(ip, a.config.ifi)
+			if err != nil {
+				conn.Close()
+
+				return nil, fmt.Errorf("listening for announcements: %w", err)
+			}
+		}
+
+		a.groups[ip.String()] = g
+	}
+
+	an := &Announcement{
+		announcer: a,
+		group:     g,
+		packet:    packet,
+		raw:       raw,
+		next:      start,
+		end:       end,
+	}
+
+	g.announcements = append(g.announcements, an)
+
+	a.wakeup()
+
+	return an, nil
+}
+
+// Remove stops announcing an and sends a deletion packet for it.
+func (a *Announcer) Remove(an *Announcement) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.closed {
+		return ErrAnnouncerClosed
+	}
+
+	g := an.group
+
+	if !g.remove(an) {
+		return ErrUnknownAnnouncement
+	}
+
+	err := g.sendDeletion(an)
+
+	if len(g.announcements) == 0 {
+		g.close()
+		delete(a.groups, g.ip.String())
+	}
+
+	return err
+}
+
+func (a *Announcer) Announcements() []*Announcement {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	announcements := make([]*Announcement, 0)
+
+	for _, g := range a.groups {
+		announcements = append(announcements, g.announcements...)
+	}
+
+	return announcements
+}
+
+// sendError is a packet that could not be sent to a group.
+type sendError struct {
+	group net.IP
+	err   error
+}
+
+// sendDue sends all announcements that are due and returns the time until the
+// next one is, along with the packets that failed. Failed announcements are
+// rescheduled like sent ones, so one broken group doesn't stop the others.
+func (a *Announcer) sendDue(now time.Time) (time.Duration, []sendError) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	wait := idleWakeupInterval
+
+	var failed []sendError
+
+	for key, g := range a.groups {
+		interval := g.interval(a.config.minInterval)
+
+		for _, an := range slices.Clone(g.announcements) {
+			if !an.end.IsZero() && !now.Before(an.end) {
+				g.remove(an)
+
+				if err := g.sendDeletion(an); err != nil {
+					failed = append(failed, sendError{g.ip, err})
+				}
+
+				continue
+			}
+
+			if !an.next.After(now) {
+				if _, err := g.conn.Write(an.raw); err != nil {
+					failed = append(failed, sendError{g.ip, fmt.Errorf("sending announcement package: %w", err)})
+				}
+
+				an.next = now.Add(interval + announcementOffset(interval))
+			}
+
+			if d := an.next.Sub(now); d < wait {
+				wait = d
+			}
+
+			if d := an.end.Sub(now); !an.end.IsZero() && d < wait {
+				wait = d
+			}
+		}
+
+		if len(g.announcements) == 0 {
+			g.close()
+			delete(a.groups, key)
+		}
+	}
+
+	return wait, failed
+}
+
+// close sends deletion packets for all announcements and closes all sockets.
+func (a *Announcer) close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var err error
+
+	for _, g := range a.groups {
+		for _, an := range g.announcements {
+			if e := g.sendDeletion(an); e != nil && err == nil {
+				err = e
+			}
+		}
+
+		g.close()
+	}
+
+	a.groups = make(map[string]*announcerGroup)
+	a.closed = true
+
+	return err
+}
+
+// Run sends announcements until ctx is done. Packets that fail to send are
+// reported to the handler of WithErrorHandler. Before returning, deletion
+// packets are sent for all sessions.
+func (a *Announcer) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		wait, failed := a.sendDue(time.Now())
+
+		if a.config.errorHandler != nil {
+			for _, f := range failed {
+				a.config.errorHandler(f.group, f.err)
+			}
+		}
+
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			if err := a.close(); err != nil {
+				return err
+			}
+
+			return ctx.Err()
+
+		case <-a.wake:
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func listenLoopback(t *testing.T) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{
+		IP:   net.IPv4(127, 0, 0, 1),
+		Port: sapPort,
+	})
+	if err != nil {
+		t.Skipf("cannot listen on SAP port: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return conn
+}
+
+func readTestPacket(t *testing.T, conn *net.UDPConn) *Packet {
+	t.Helper()
+
+	buf := make([]byte, maxDatagramSize)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("reading packet: %v", err)
+	}
+
+	p, err := DecodePacket(buf[:n])
+	if err != nil {
+		t.Fatalf("DecodePacket() error = %v", err)
+	}
+
+	return p
+}
+
+func TestAnnouncer(t *testing.T) {
+	conn := listenLoopback(t)
+
+	a := NewAnnouncer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	ip := net.IPv4(127, 0, 0, 1)
+
+	first, err := a.Add(ip, testPacket(0x0001))
+	if err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	if p := readTestPacket(t, conn); p.IDHash != 0x0001 || p.Type != MessageTypeAnnouncement {
+		t.Errorf("got packet %04x type %d, want announcement of 0001", p.IDHash, p.Type)
+	}
+
+	if _, err := a.Add(ip, testPacket(0x0002)); err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	if p := readTestPacket(t, conn); p.IDHash != 0x0002 || p.Type != MessageTypeAnnouncement {
+		t.Errorf("got packet %04x type %d, want announcement of 0002", p.IDHash, p.Type)
+	}
+
+	if err := a.Remove(first); err != nil {
+		t.Fatalf("Announcer.Remove() error = %v", err)
+	}
+
+	if p := readTestPacket(t, conn); p.IDHash != 0x0001 || p.Type != MessageTypeDeletion {
+		t.Errorf("got packet %04x type %d, want deletion of 0001", p.IDHash, p.Type)
+	}
+
+	if err := a.Remove(first); !errors.Is(err, ErrUnknownAnnouncement) {
+		t.Errorf("Announcer.Remove() error = %v, want %v", err, ErrUnknownAnnouncement)
+	}
+
+	cancel()
+
+	if p := readTestPacket(t, conn); p.IDHash != 0x0002 || p.Type != MessageTypeDeletion {
+		t.Errorf("got packet %04x type %d, want deletion of 0002", p.IDHash, p.Type)
+	}
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Announcer.Run() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAnnouncerGroup_interval(t *testing.T) {
+	g := &announcerGroup{}
+
+	for i := 0; i < 10; i++ {
+		g.announcements = append(g.announcements, &Announcement{
+			raw: make([]byte, 1000),
+		})
+	}
+
+	// 10 * 1000 bytes * 8 bits / 4000 bits/s = 20s
+	if got := g.interval(time.Second); got != 20*time.Second {
+		t.Errorf("announcerGroup.interval() = %v, want %v", got, 20*time.Second)
+	}
+
+	if got := g.interval(minIntervalDefault); got != minIntervalDefault {
+		t.Errorf("announcerGroup.interval() = %v, want %v", got, minIntervalDefault)
+	}
+}
+
+func TestAnnouncerGroup_adaptiveInterval(t *testing.T) {
+	own := testPacket(0x0001)
+	own.Payload = make([]byte, 1000)
+
+	ownRaw, err := own.Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	g := &announcerGroup{
+		traffic: &trafficMonitor{
+			directory: NewDirectory(),
+			sizes:     make(map[string]int),
+		},
+		announcements: []*Announcement{{
+			packet: *own,
+			raw:    ownRaw,
+		}},
+	}
+
+	// Our own announcement looped back must not be counted twice
+	g.traffic.handle(ownRaw)
+
+	want := time.Duration(8*len(ownRaw)/bandwidthLimitBits) * time.Second
+
+	if got := g.interval(time.Second); got != want {
+		t.Errorf("announcerGroup.interval() = %v, want %v", got, want)
+	}
+
+	size := len(ownRaw)
+
+	for i := 0; i < 100; i++ {
+		other := testPacket(uint16(0x1000 + i))
+		other.Payload = make([]byte, 1000)
+
+		raw, err := other.Encode()
+		if err != nil {
+			t.Fatalf("Packet.Encode() error = %v", err)
+		}
+
+		g.traffic.handle(raw)
+		size += len(raw)
+	}
+
+	want = time.Duration(8*size/bandwidthLimitBits) * time.Second
+
+	if got := g.interval(time.Second); got != want {
+		t.Errorf("announcerGroup.interval() = %v, want %v", got, want)
+	}
+}
+
+func TestAnnouncement_Update(t *testing.T) {
+	conn := listenLoopback(t)
+
+	a := NewAnnouncer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	an, err := a.Add(net.IPv4(127, 0, 0, 1), testPacket(0x0001))
+	if err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	readTestPacket(t, conn)
+
+	payload := []byte("v=0\r\ns=updated\r\n")
+
+	// Packet may be read while the announcement is updated
+	reading := make(chan struct{})
+
+	go func() {
+		defer close(reading)
+
+		for i := 0; i < 100; i++ {
+			_ = an.Packet()
+		}
+	}()
+
+	if err := an.Update(payload); err != nil {
+		t.Fatalf("Announcement.Update() error = %v", err)
+	}
+
+	<-reading
+
+	p := readTestPacket(t, conn)
+
+	if p.Type != MessageTypeAnnouncement || string(p.Payload) != string(payload) {
+		t.Errorf("got packet type %d payload %q, want announcement of %q", p.Type, p.Payload, payload)
+	}
+
+	if p.IDHash == 0x0001 || p.IDHash == 0 {
+		t.Errorf("Packet.IDHash = %04x, want a new hash", p.IDHash)
+	}
+
+	if an.Packet().IDHash != p.IDHash {
+		t.Errorf("Announcement.Packet().IDHash = %04x, want %04x", an.Packet().IDHash, p.IDHash)
+	}
+
+	cancel()
+
+	// The deletion refers to the updated session only
+	if d := readTestPacket(t, conn); d.Type != MessageTypeDeletion || d.IDHash != p.IDHash {
+		t.Errorf("got packet %04x type %d, want deletion of %04x", d.IDHash, d.Type, p.IDHash)
+	}
+
+	<-done
+
+	if err := an.Update(payload); !errors.Is(err, ErrAnnouncerClosed) {
+		t.Errorf("Announcement.Update() error = %v, want %v", err, ErrAnnouncerClosed)
+	}
+}
+
+func TestAnnouncer_sendError(t *testing.T) {
+	failed := make(chan net.IP, 1)
+
+	a := NewAnnouncer(WithErrorHandler(func(group net.IP, err error) {
+		select {
+		case failed <- group:
+		default:
+		}
+	}))
+
+	ip := net.IPv4(127, 0, 0, 1)
+
+	an, err := a.Add(ip, testPacket(0x0001))
+	if err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	// Every write to the group fails from now on
+	an.group.conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	select {
+	case group := <-failed:
+		if !group.Equal(ip) {
+			t.Errorf("error handler called for group %v, want %v", group, ip)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatal("error handler not called")
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Announcer.Run() returned %v after a failed write", err)
+
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if n := len(a.Announcements()); n != 1 {
+		t.Errorf("len(Announcer.Announcements()) = %d, want 1", n)
+	}
+
+	if wait, _ := a.sendDue(time.Now()); wait <= 0 {
+		t.Errorf("Announcer.sendDue() = %v, want the failed announcement rescheduled", wait)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,254 @@
+package sap
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+type AuthenticationType uint8
+
+// RFC 2974, section 7.1
+const (
+	AuthenticationTypePGP = AuthenticationType(0)
+	AuthenticationTypeCMS = AuthenticationType(1)
+)
+
+const (
+	authenticationVersion     = 1
+	authenticationPaddingFlag = uint8(1 << 4)
+)
+
+var ErrAuthenticationDataUnaligned = errors.New("authentication data not a multiple of 32 bits")
+var ErrInvalidAuthenticationHeader = errors.New("invalid authentication header")
+var ErrNotSigned = errors.New("packet not signed")
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Authenticator signs packets and verifies their signatures. The signed data
+// is the encoded packet without its authentication data, as RFC 2974,
+// section 7 describes.
+type Authenticator interface {
+	// Sign returns the authentication header carrying a signature over the
+	// signed data.
+	Sign(signed []byte) (*AuthenticationHeader, error)
+
+	// Verify checks the signature in h against the signed data and returns
+	// an identifier of the key that made it.
+	Verify(signed []byte, h *AuthenticationHeader) (string, error)
+}
+
+// AuthenticationError is returned for packets that fail verification. Err is
+// ErrNotSigned, ErrInvalidSignature or a decoding error of the authentication
+// data.
+type AuthenticationError struct {
+	Err error
+}
+
+func (e *AuthenticationError) Error() string {
+	return "authentication failed: " + e.Err.Error()
+}
+
+func (e *AuthenticationError) Unwrap() error {
+	return e.Err
+}
+
+// AuthenticationHeader is the content of the authentication data of a packet
+// as described in RFC 2974, section 7.1. Data is the format specific
+// subheader, such as a PGP or CMS signature, without padding.
+type AuthenticationHeader struct {
+	Type AuthenticationType
+	Data []byte
+}
+
+// DecodeAuthenticationHeader decodes the authentication data of a packet.
+func DecodeAuthenticationHeader(b []byte) (*AuthenticationHeader, error) {
+	if len(b) == 0 {
+		return nil, errors.Wrap(ErrInvalidAuthenticationHeader, "empty")
+	}
+
+	if len(b)%4 != 0 {
+		return nil, ErrAuthenticationDataUnaligned
+	}
+
+	// RFC 2974, section 7.1:
+	// | V=1 |P| Auth  | format specific authentication subheader ...
+	if version := b[0] >> 5; version != authenticationVersion {
+		return nil, errors.Wrapf(ErrInvalidAuthenticationHeader, "version %d", version)
+	}
+
+	h := &AuthenticationHeader{
+		Type: AuthenticationType(b[0] & 0x0f),
+	}
+
+	data := b[1:]
+
+	// If the padding bit is set, the last byte holds the number of padding
+	// bytes including itself.
+	if b[0]&authenticationPaddingFlag != 0 {
+		padding := int(data[len(data)-1])
+
+		if padding == 0 || padding > len(data) {
+			return nil, errors.Wrapf(ErrInvalidAuthenticationHeader, "%d padding bytes", padding)
+		}
+
+		data = data[:len(data)-padding]
+	}
+
+	h.Data = make([]byte, len(data))
+	copy(h.Data, data)
+
+	return h, nil
+}
+
+// Encode returns the authentication data for a packet, padded to a multiple
+// of 32 bits.
+func (h *AuthenticationHeader) Encode() ([]byte, error) {
+	if h.Type > 0x0f {
+		return nil, errors.Wrapf(ErrInvalidAuthenticationHeader, "type %d", h.Type)
+	}
+
+	b := make([]byte, 0, len(h.Data)+4)
+	b = append(b, authenticationVersion<<5|uint8(h.Type))
+	b = append(b, h.Data...)
+
+	if n := len(b) % 4; n != 0 {
+		padding := 4 - n
+
+		b[0] |= authenticationPaddingFlag
+		b = append(b, make([]byte, padding-1)...)
+		b = append(b, uint8(padding))
+	}
+
+	if len(b) > maxAuthenticationDataSize {
+		return nil, ErrAuthenticationDataTooLong
+	}
+
+	return b, nil
+}
+
+// AuthenticationHeader decodes the authentication data of the packet. It
+// returns nil if the packet carries none.
+func (p *Packet) AuthenticationHeader() (*AuthenticationHeader, error) {
+	if len(p.AuthenticationData) == 0 {
+		return nil, nil
+	}
+
+	return DecodeAuthenticationHeader(p.AuthenticationData)
+}
+
+// RFC 2974, section 7:
+// Signatures are calculated over the entire packet, with the authentication
+// length set to zero and the authentication data excluded.
+func (p *Packet) signedData() ([]byte, error) {
+	unsigned := *p
+	unsigned.AuthenticationData = nil
+
+	return unsigned.Encode()
+}
+
+// signedData returns the part of an encoded packet covered by its signature,
+// along with the authentication data.
+func signedData(raw []byte) ([]byte, []byte, error) {
+	if len(raw) < 4 {
+		return nil, nil, ErrPacketTooShort
+	}
+
+	headerLen := 4 + net.IPv4len
+	if raw[0]&addressV6Flag != 0 {
+		headerLen = 4 + net.IPv6len
+	}
+
+	authLen := int(raw[1]) * 4
+
+	if len(raw) < headerLen+authLen {
+		return nil, nil, ErrPacketTooShort
+	}
+
+	signed := make([]byte, 0, len(raw)-authLen)
+	signed = append(signed, raw[0], 0)
+	signed = append(signed, raw[2:headerLen]...)
+	signed = append(signed, raw[headerLen+authLen:]...)
+
+	return signed, raw[headerLen : headerLen+authLen], nil
+}
+
+// withAuthenticationData inserts aligned authentication data into an encoded
+// packet that has none, undoing signedData.
+func withAuthenticationData(signed, auth []byte) []byte {
+	headerLen := 4 + net.IPv4len
+	if signed[0]&addressV6Flag != 0 {
+		headerLen = 4 + net.IPv6len
+	}
+
+	raw := make([]byte, 0, len(signed)+len(auth))
+	raw = append(raw, signed[0], uint8(len(auth)/4))
+	raw = append(raw, signed[2:headerLen]...)
+	raw = append(raw, auth...)
+	raw = append(raw, signed[headerLen:]...)
+
+	return raw
+}
+
+// Sign sets the authentication data of p to a signature over the packet.
+// Packets must be signed again after any change, including the message type.
+// Encrypted packets get a new random prefix each time they are encoded, so
+// their signature has to be made while encoding, see WithAuthenticator, and
+// Sign fails with ErrNoKey.
+func (p *Packet) Sign(a Authenticator) error {
+	signed, err := p.signedData()
+	if err != nil {
+		return err
+	}
+
+	h, err := a.Sign(signed)
+	if err != nil {
+		return err
+	}
+
+	return p.SetAuthenticationHeader(h)
+}
+
+// VerifyPacket checks the signature of an encoded packet and returns an
+// identifier of the key that made it. Failures are reported as
+// *AuthenticationError.
+func VerifyPacket(raw []byte, a Authenticator) (string, error) {
+	signed, auth, err := signedData(raw)
+	if err != nil {
+		return "", &AuthenticationError{Err: err}
+	}
+
+	if len(auth) == 0 {
+		return "", &AuthenticationError{Err: ErrNotSigned}
+	}
+
+	h, err := DecodeAuthenticationHeader(auth)
+	if err != nil {
+		return "", &AuthenticationError{Err: err}
+	}
+
+	signer, err := a.Verify(signed, h)
+	if err != nil {
+		return "", &AuthenticationError{Err: err}
+	}
+
+	return signer, nil
+}
+
+// SetAuthenticationHeader encodes h as authentication data of the packet, or
+// removes the authentication data if h is nil.
+func (p *Packet) SetAuthenticationHeader(h *AuthenticationHeader) error {
+	if h == nil {
+		p.AuthenticationData = nil
+
+		return nil
+	}
+
+	b, err := h.Encode()
+	if err != nil {
+		return err
+	}
+
+	p.AuthenticationData = b
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestAuthenticationHeader_Encode(t *testing.T) {
+	tests := []struct {
+		name   string
+		header AuthenticationHeader
+		want   []byte
+	}{
+		{
+			name:   "aligned",
+			header: AuthenticationHeader{Type: AuthenticationTypePGP, Data: []byte{1, 2, 3}},
+			want:   []byte{0x20, 1, 2, 3},
+		},
+		{
+			name:   "padded",
+			header: AuthenticationHeader{Type: AuthenticationTypeCMS, Data: []byte{1, 2, 3, 4}},
+			want:   []byte{0x31, 1, 2, 3, 4, 0, 0, 3},
+		},
+		{
+			name:   "one padding byte",
+			header: AuthenticationHeader{Type: AuthenticationTypeCMS, Data: []byte{1, 2}},
+			want:   []byte{0x31, 1, 2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.header.Encode()
+			if err != nil {
+				t.Fatalf("AuthenticationHeader.Encode() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuthenticationHeader.Encode() = %v, want %v", got, tt.want)
+			}
+
+			back, err := DecodeAuthenticationHeader(got)
+			if err != nil {
+				t.Fatalf("DecodeAuthenticationHeader() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(*back, tt.header) {
+				t.Errorf("DecodeAuthenticationHeader() = %v, want %v", back, tt.header)
+			}
+		})
+	}
+
+	h := AuthenticationHeader{Data: make([]byte, maxAuthenticationDataSize)}
+	if _, err := h.Encode(); err != ErrAuthenticationDataTooLong {
+		t.Errorf("AuthenticationHeader.Encode() error = %v, want %v", err, ErrAuthenticationDataTooLong)
+	}
+}
+
+func TestDecodeAuthenticationHeader_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		want error
+	}{
+		{"unaligned", []byte{0x20, 1, 2}, ErrAuthenticationDataUnaligned},
+		{"version", []byte{0x40, 1, 2, 3}, ErrInvalidAuthenticationHeader},
+		{"zero padding", []byte{0x31, 1, 2, 0}, ErrInvalidAuthenticationHeader},
+		{"padding too long", []byte{0x31, 1, 2, 4}, ErrInvalidAuthenticationHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeAuthenticationHeader(tt.raw); !errors.Is(err, tt.want) {
+				t.Errorf("DecodeAuthenticationHeader() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPacket_authentication(t *testing.T) {
+	p := &Packet{
+		Type:        MessageTypeAnnouncement,
+		IDHash:      0x0001,
+		Origin:      net.ParseIP("192.168.100.254"),
+		PayloadType: SDPPayloadType,
+		Payload:     []byte("v=0\r\n"),
+	}
+
+	if err := p.SetAuthenticationHeader(&AuthenticationHeader{
+		Type: AuthenticationTypeCMS,
+		Data: []byte{1, 2, 3, 4, 5},
+	}); err != nil {
+		t.Fatalf("Packet.SetAuthenticationHeader() error = %v", err)
+	}
+
+	raw, err := p.Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	// The length is given in 32-bit words
+	if raw[1] != 2 {
+		t.Errorf("authentication length = %d, want 2", raw[1])
+	}
+
+	back, err := DecodePacket(raw)
+	if err != nil {
+		t.Fatalf("DecodePacket() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(p, back) {
+		t.Errorf("DecodePacket() = %v, want %v", back, p)
+	}
+
+	h, err := back.AuthenticationHeader()
+	if err != nil {
+		t.Fatalf("Packet.AuthenticationHeader() error = %v", err)
+	}
+
+	if h.Type != AuthenticationTypeCMS || !reflect.DeepEqual(h.Data, []byte{1, 2, 3, 4, 5}) {
+		t.Errorf("Packet.AuthenticationHeader() = %v", h)
+	}
+
+	p.AuthenticationData = []byte{0x20, 1, 2}
+	if _, err := p.Encode(); err != ErrAuthenticationDataUnaligned {
+		t.Errorf("Packet.Encode() error = %v, want %v", err, ErrAuthenticationDataUnaligned)
+	}
+
+	// Padding count exceeding the authentication data
+	raw[1+1+2+4+7] = 8
+	if _, err := DecodePacket(raw); !errors.Is(err, ErrInvalidAuthenticationHeader) {
+		t.Errorf("DecodePacket() error = %v, want %v", err, ErrInvalidAuthenticationHeader)
+	}
+}
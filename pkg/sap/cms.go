@@ -0,0 +1,97 @@
+package sap
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+
+	"github.com/digitorus/pkcs7"
+	"github.com/pkg/errors"
+)
+
+// CMSAuthenticator implements the CMS authentication type of RFC 2974,
+// section 7.1. Signatures are detached CMS SignedData structures (RFC 5652)
+// that carry the certificate and intermediates, so keep them small: with RSA
+// keys, a signature easily exceeds the 1020 bytes the authentication data can
+// hold. ECDSA keys are a good fit.
+//
+// Certificate and Key are needed for signing. Verification checks that the
+// certificate of a signature chains up to one of the Roots, using the
+// Intermediates if the signature does not include them.
+type CMSAuthenticator struct {
+	Certificate   *x509.Certificate
+	Intermediates []*x509.Certificate
+	Key           crypto.Signer
+	Roots         *x509.CertPool
+}
+
+func (a *CMSAuthenticator) Sign(signed []byte) (*AuthenticationHeader, error) {
+	sd, err := pkcs7.NewSignedData(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+
+	if err := sd.AddSignerChain(a.Certificate, a.Key, a.Intermediates, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, errors.Wrap(err, "signing packet")
+	}
+
+	sd.Detach()
+
+	der, err := sd.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthenticationHeader{
+		Type: AuthenticationTypeCMS,
+		Data: der,
+	}, nil
+}
+
+// Verify returns the SHA-256 fingerprint of the public key of the signing
+// certificate.
+func (a *CMSAuthenticator) Verify(signed []byte, h *AuthenticationHeader) (string, error) {
+	if h.Type != AuthenticationTypeCMS {
+		return "", errors.Wrapf(ErrInvalidSignature, "authentication type %d", h.Type)
+	}
+
+	p7, err := pkcs7.Parse(h.Data)
+	if err != nil {
+		return "", errors.Wrapf(ErrInvalidSignature, "%v", err)
+	}
+
+	p7.Content = signed
+
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range append(a.Intermediates, p7.Certificates...) {
+		intermediates.AddCert(cert)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         a.Roots,
+		Intermediates: intermediates,
+	}
+
+	if a.Roots == nil {
+		// An empty trust store rather than the system roots, which have no
+		// business vouching for announcements.
+		opts.Roots = x509.NewCertPool()
+	}
+
+	if err := p7.VerifyWithOpts(opts); err != nil {
+		return "", errors.Wrapf(ErrInvalidSignature, "%v", err)
+	}
+
+	cert := p7.GetOnlySigner()
+	if cert == nil {
+		return "", errors.Wrap(ErrInvalidSignature, "not exactly one signer")
+	}
+
+	fingerprint := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return hex.EncodeToString(fingerprint[:]), nil
+}
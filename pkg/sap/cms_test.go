@@ -0,0 +1,180 @@
+package sap
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %v", err)
+	}
+
+	return cert, key
+}
+
+func testCMS(t *testing.T) *CMSAuthenticator {
+	t.Helper()
+
+	ca, caKey := testCertificate(t, "Test CA", nil, nil)
+	cert, key := testCertificate(t, "Announcer", ca, caKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	return &CMSAuthenticator{
+		Certificate: cert,
+		Key:         key,
+		Roots:       roots,
+	}
+}
+
+func TestCMSAuthenticator(t *testing.T) {
+	a := testCMS(t)
+
+	p := testPacket(0x0001)
+
+	if err := p.Sign(a); err != nil {
+		t.Fatalf("Packet.Sign() error = %v", err)
+	}
+
+	raw, err := p.Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	signer, err := VerifyPacket(raw, a)
+	if err != nil {
+		t.Fatalf("VerifyPacket() error = %v", err)
+	}
+
+	fingerprint := sha256.Sum256(a.Certificate.RawSubjectPublicKeyInfo)
+
+	if want := hex.EncodeToString(fingerprint[:]); signer != want {
+		t.Errorf("VerifyPacket() = %s, want %s", signer, want)
+	}
+
+	h, err := p.AuthenticationHeader()
+	if err != nil || h.Type != AuthenticationTypeCMS {
+		t.Errorf("Packet.AuthenticationHeader() = %v, %v, want CMS", h, err)
+	}
+
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-3] ^= 0xff
+
+	if _, err := VerifyPacket(tampered, a); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyPacket() error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// The message type is covered by the signature
+	tampered = append([]byte{}, raw...)
+	tampered[0] |= messageTypeDeletion
+
+	if _, err := VerifyPacket(tampered, a); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyPacket() error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	other := testCMS(t)
+
+	if _, err := VerifyPacket(raw, other); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyPacket() with other roots error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	unsigned, err := testPacket(0x0001).Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	var authErr *AuthenticationError
+
+	if _, err := VerifyPacket(unsigned, a); !errors.As(err, &authErr) || authErr.Err != ErrNotSigned {
+		t.Errorf("VerifyPacket() error = %v, want %v", err, ErrNotSigned)
+	}
+}
+
+func TestAnnouncer_authenticator(t *testing.T) {
+	conn := listenLoopback(t)
+
+	auth := testCMS(t)
+
+	a := NewAnnouncer(WithAuthenticator(auth))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	if _, err := a.Add(net.IPv4(127, 0, 0, 1), testPacket(0x0001)); err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	buf := make([]byte, maxDatagramSize)
+
+	for _, want := range []MessageType{MessageTypeAnnouncement, MessageTypeDeletion} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("reading packet: %v", err)
+		}
+
+		if _, err := VerifyPacket(buf[:n], auth); err != nil {
+			t.Errorf("VerifyPacket() error = %v", err)
+		}
+
+		p, err := DecodePacket(buf[:n])
+		if err != nil {
+			t.Fatalf("DecodePacket() error = %v", err)
+		}
+
+		if p.Type != want {
+			t.Errorf("got packet type %d, want %d", p.Type, want)
+		}
+
+		cancel()
+	}
+
+	<-done
+}
@@ -0,0 +1,625 @@
+package sap
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrDeletionUnauthorized = errors.New("deletion not authorized")
+var ErrAnnouncementUnauthorized = errors.New("announcement not authorized")
+
+const (
+	// RFC 2974, section 4
+	sessionTimeoutMinimum    = time.Hour
+	sessionTimeoutIntervals  = 10
+	intervalSmoothingDivisor = 4
+
+	// Copies of an announcement received within duplicateWindow of each
+	// other, such as those MultiListener reads on several interfaces, are
+	// one announcement.
+	duplicateWindow = time.Second
+
+	expiryCheckInterval = 10 * time.Second
+	subscriberQueueSize = 16
+)
+
+type EventType int
+
+const (
+	EventTypeAdded = EventType(iota)
+	EventTypeUpdated
+	EventTypeDeleted
+	EventTypeExpired
+	EventTypeDeletionRejected
+	EventTypeAnnouncementRejected
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventTypeAdded:
+		return "added"
+	case EventTypeUpdated:
+		return "updated"
+	case EventTypeDeleted:
+		return "deleted"
+	case EventTypeExpired:
+		return "expired"
+	case EventTypeDeletionRejected:
+		return "deletion-rejected"
+	case EventTypeAnnouncementRejected:
+		return "announcement-rejected"
+	}
+
+	return "unknown"
+}
+
+// Event describes a change of a session in the directory. Old is nil for
+// EventTypeAdded, New is nil for EventTypeExpired and carries the deletion
+// packet for EventTypeDeleted. For EventTypeUpdated, Old and New may have
+// different unique IDs if the session was re-announced with a new message ID
+// hash.
+//
+// EventTypeDeletionRejected and EventTypeAnnouncementRejected report packets
+// for a known session refused by the policy of WithDeletionAuthorization. The
+// session is left unchanged, New carries the refused packet, Datagram the
+// datagram it arrived in, if any, and Err the reason.
+type Event struct {
+	Type     EventType
+	Session  Session
+	Old      *Packet
+	New      *Packet
+	Datagram *Datagram
+	Err      error
+}
+
+type Session struct {
+	Packet    *Packet
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Interval  time.Duration
+	Count     int
+
+	// Source, IfIndex and Signer are only known for sessions fed by
+	// HandleDatagram. Signer is empty unless the datagram was verified, see
+	// Datagram.Signer.
+	Source  *net.UDPAddr
+	IfIndex int
+	Signer  string
+
+	// Identity is the SDP origin of the session without its version, see
+	// sdp.Origin.Identity. It is empty if the payload is not SDP. Packets
+	// with the same identity and SAP origin belong to the same session.
+	Identity string
+
+	// End is the stop time given by the t= lines of the SDP, or the timeout
+	// of an encrypted announcement that could not be decrypted. It is zero if
+	// the session is unbounded.
+	End time.Time
+
+	version uint64
+}
+
+// Timeout returns the duration after the last announcement at which the session
+// is considered gone.
+func (s *Session) Timeout() time.Duration {
+	// RFC 2974, section 4:
+	// Sessions time out after ten times the announcement interval or one
+	// hour, whichever is the greater.
+	timeout := sessionTimeoutIntervals * s.Interval
+
+	if timeout < sessionTimeoutMinimum {
+		timeout = sessionTimeoutMinimum
+	}
+
+	return timeout
+}
+
+// ExpiresAt returns the time at which the session times out, or ends
+// according to its SDP, whichever comes first.
+func (s *Session) ExpiresAt() time.Time {
+	expires := s.LastSeen.Add(s.Timeout())
+
+	if !s.End.IsZero() && s.End.Before(expires) {
+		return s.End
+	}
+
+	return expires
+}
+
+// duplicate reports whether p is another copy of the last announcement rather
+// than the next one, so it doesn't count towards the interval estimate. Copies
+// from a different interface may lag behind further, but still arrive well
+// within an interval.
+func (s *Session) duplicate(p *Packet, now time.Time, dg *Datagram) bool {
+	if p.IDHash != s.Packet.IDHash || payloadChanged(s.Packet, p) {
+		return false
+	}
+
+	observed := now.Sub(s.LastSeen)
+
+	if observed < duplicateWindow {
+		return true
+	}
+
+	return dg != nil && dg.IfIndex != s.IfIndex && s.Count > 1 && observed < s.Interval/2
+}
+
+// update refreshes the session with an announcement. If pinned is set, the
+// source and signer are only taken from the datagram if none is known yet.
+// Duplicates leave the session as it is.
+func (s *Session) update(p *Packet, now time.Time, dg *Datagram, pinned bool) {
+	if s.duplicate(p, now, dg) {
+		return
+	}
+
+	observed := now.Sub(s.LastSeen)
+
+	switch {
+	case s.Count == 1:
+		s.Interval = observed
+	case observed > 0:
+		// Exponentially weighted moving average to smooth out the random
+		// offset announcers apply to their interval.
+		s.Interval += (observed - s.Interval) / intervalSmoothingDivisor
+	}
+
+	s.Packet = p
+	s.LastSeen = now
+	s.Count++
+
+	if dg != nil {
+		s.IfIndex = dg.IfIndex
+
+		if !pinned || s.Source == nil {
+			s.Source = dg.Source
+			s.Signer = dg.Signer
+		}
+	}
+}
+
+func payloadChanged(old, new *Packet) bool {
+	return old.PayloadType != new.PayloadType || !bytes.Equal(old.Payload, new.Payload)
+}
+
+type subscriber struct {
+	ctx context.Context
+	ch  chan Event
+}
+
+type Directory struct {
+	// dispatchMutex serializes state changes together with the delivery of
+	// the resulting events, so subscribers see events in order. mutex only
+	// guards the session map, so subscribers may query the directory while
+	// handling an event.
+	dispatchMutex sync.Mutex
+	mutex         sync.Mutex
+	sessions      map[string]*Session
+	identities    map[string]string
+	subscribers   []*subscriber
+	now           func() time.Time
+	keyring       Keyring
+
+	deletionAuthorization bool
+}
+
+// DirectoryOption configures a Directory.
+type DirectoryOption func(d *Directory)
+
+// WithDecryptionKeyring makes HandleDatagram decrypt encrypted announcements
+// with the keys of k.
+func WithDecryptionKeyring(k Keyring) DirectoryOption {
+	return func(d *Directory) {
+		d.keyring = k
+	}
+}
+
+// WithDeletionAuthorization makes the directory only honor deletions sent from
+// the source IP address of the first announcement of the session and, if that
+// announcement was signed, signed by the same key. Anyone able to spoof an
+// origin and message ID hash could delete sessions otherwise. Later
+// announcements must pass the same checks to refresh or replace the session,
+// so the source cannot be taken over either. Refused packets are reported as
+// EventTypeDeletionRejected and EventTypeAnnouncementRejected. Packets
+// passed to Handle instead of HandleDatagram come without a source, so
+// deletions are always rejected, as are announcements for sessions whose
+// source is known.
+func WithDeletionAuthorization() DirectoryOption {
+	return func(d *Directory) {
+		d.deletionAuthorization = true
+	}
+}
+
+// NewDirectory returns an empty directory.
+func NewDirectory(opts ...DirectoryOption) *Directory {
+	d := &Directory{
+		sessions:   make(map[string]*Session),
+		identities: make(map[string]string),
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Subscribe returns a channel that receives all session events until ctx is
+// done, at which point the channel is closed. Slow subscribers block the
+// directory.
+func (d *Directory) Subscribe(ctx context.Context) <-chan Event {
+	s := &subscriber{
+		ctx: ctx,
+		ch:  make(chan Event, subscriberQueueSize),
+	}
+
+	d.dispatchMutex.Lock()
+	d.subscribers = append(d.subscribers, s)
+	d.dispatchMutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		d.dispatchMutex.Lock()
+		defer d.dispatchMutex.Unlock()
+
+		for i, other := range d.subscribers {
+			if other == s {
+				d.subscribers = append(d.subscribers[:i], d.subscribers[i+1:]...)
+				break
+			}
+		}
+
+		close(s.ch)
+	}()
+
+	return s.ch
+}
+
+func (d *Directory) dispatch(events []Event) {
+	for _, e := range events {
+		for _, s := range d.subscribers {
+			select {
+			case s.ch <- e:
+			case <-s.ctx.Done():
+			}
+		}
+	}
+}
+
+// Handle feeds a decoded packet into the directory. Announcements create or
+// refresh a session, deletions remove it.
+func (d *Directory) Handle(p *Packet) {
+	d.dispatchMutex.Lock()
+	defer d.dispatchMutex.Unlock()
+
+	d.dispatch(d.handle(p, nil))
+}
+
+// HandleDatagram decodes a received datagram and feeds it into the directory.
+// The reception timestamp is used to estimate the announcement interval, and
+// the source address and interface are recorded in the session.
+func (d *Directory) HandleDatagram(dg *Datagram) (*Packet, error) {
+	p, err := DecodePacketWithKeyring(dg.Data, d.keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	d.dispatchMutex.Lock()
+	defer d.dispatchMutex.Unlock()
+
+	d.dispatch(d.handle(p, dg))
+
+	return p, nil
+}
+
+func (d *Directory) handle(p *Packet, dg *Datagram) []Event {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	id := p.UniqueID()
+	now := d.now()
+
+	if dg != nil && !dg.Timestamp.IsZero() {
+		now = dg.Timestamp
+	}
+
+	s, ok := d.sessions[id]
+
+	if p.Type == MessageTypeDeletion {
+		if !ok {
+			return nil
+		}
+
+		if err := d.authorize(s, dg, ErrDeletionUnauthorized); err != nil {
+			return []Event{{
+				Type:     EventTypeDeletionRejected,
+				Session:  *s,
+				Old:      s.Packet,
+				New:      p,
+				Datagram: dg,
+				Err:      err,
+			}}
+		}
+
+		d.remove(id, s)
+
+		return []Event{{
+			Type:    EventTypeDeleted,
+			Session: *s,
+			Old:     s.Packet,
+			New:     p,
+		}}
+	}
+
+	if !ok {
+		desc := describeSession(p)
+
+		// Announcements of sessions that are over would only be expired
+		// right away.
+		if !desc.end.IsZero() && now.After(desc.end) {
+			return nil
+		}
+
+		key := identityKey(p, desc.identity)
+
+		if currentID, found := d.identities[key]; found && desc.identity != "" {
+			return d.replace(currentID, id, desc, p, now, dg)
+		}
+
+		s = &Session{
+			Packet:    p,
+			FirstSeen: now,
+			LastSeen:  now,
+			Count:     1,
+			Identity:  desc.identity,
+			End:       desc.end,
+			version:   desc.version,
+		}
+
+		if dg != nil {
+			s.Source = dg.Source
+			s.IfIndex = dg.IfIndex
+			s.Signer = dg.Signer
+		}
+
+		d.sessions[id] = s
+
+		if desc.identity != "" {
+			d.identities[key] = id
+		}
+
+		return []Event{{
+			Type:    EventTypeAdded,
+			Session: *s,
+			New:     p,
+		}}
+	}
+
+	if s.Source != nil {
+		if err := d.authorize(s, dg, ErrAnnouncementUnauthorized); err != nil {
+			return rejectAnnouncement(s, p, dg, err)
+		}
+	}
+
+	old := s.Packet
+	s.update(p, now, dg, d.deletionAuthorization)
+
+	if !payloadChanged(old, p) {
+		return nil
+	}
+
+	if desc := describeSession(p); desc.identity == s.Identity {
+		s.version = desc.version
+		s.End = desc.end
+	}
+
+	return []Event{{
+		Type:    EventTypeUpdated,
+		Session: *s,
+		Old:     old,
+		New:     p,
+	}}
+}
+
+// authorize applies the policy of WithDeletionAuthorization to a packet for
+// s, which has to come from the source of the first announcement and carry
+// the same signature. Failures wrap reason.
+func (d *Directory) authorize(s *Session, dg *Datagram, reason error) error {
+	if !d.deletionAuthorization {
+		return nil
+	}
+
+	if dg == nil || dg.Source == nil || s.Source == nil {
+		return errors.Wrap(reason, "source address unknown")
+	}
+
+	if !dg.Source.IP.Equal(s.Source.IP) {
+		return errors.Wrapf(reason, "sent from %v, announced from %v", dg.Source.IP, s.Source.IP)
+	}
+
+	if s.Signer != "" && dg.Signer != s.Signer {
+		return errors.Wrapf(reason, "signed by %q, announced by %q", dg.Signer, s.Signer)
+	}
+
+	return nil
+}
+
+func rejectAnnouncement(s *Session, p *Packet, dg *Datagram, err error) []Event {
+	return []Event{{
+		Type:     EventTypeAnnouncementRejected,
+		Session:  *s,
+		Old:      s.Packet,
+		New:      p,
+		Datagram: dg,
+		Err:      err,
+	}}
+}
+
+// sessionDescription is what the directory takes from an SDP payload.
+type sessionDescription struct {
+	identity string
+	version  uint64
+	end      time.Time
+}
+
+// describeSession returns the SDP origin identity, version and stop time of
+// an announcement, or an empty identity if the payload is not SDP. Encrypted
+// announcements that could not be decrypted still carry their timeout.
+func describeSession(p *Packet) sessionDescription {
+	desc, err := p.SDP()
+	if err != nil {
+		return sessionDescription{end: p.Timeout}
+	}
+
+	return sessionDescription{
+		identity: desc.Origin.Identity(),
+		version:  desc.Origin.SessionVersion,
+		end:      desc.End(),
+	}
+}
+
+// identityKey scopes an SDP origin identity to the SAP origin of the packet,
+// so senders that happen to share an o= line don't take over each other's
+// sessions.
+func identityKey(p *Packet, identity string) string {
+	return p.Origin.String() + " " + identity
+}
+
+// replace moves the session with the same identity from currentID to id if
+// the new announcement carries the same or a higher session version. RFC 2974,
+// section 5 requires a new message ID hash for modified sessions, so this is
+// how updates usually arrive. The same version under a new hash comes from a
+// sender that restarted and picked a new hash. Older versions are stale
+// packets and ignored.
+func (d *Directory) replace(currentID, id string, desc sessionDescription, p *Packet, now time.Time, dg *Datagram) []Event {
+	s := d.sessions[currentID]
+
+	if desc.version < s.version {
+		return nil
+	}
+
+	if s.Source != nil {
+		if err := d.authorize(s, dg, ErrAnnouncementUnauthorized); err != nil {
+			return rejectAnnouncement(s, p, dg, err)
+		}
+	}
+
+	delete(d.sessions, currentID)
+	d.sessions[id] = s
+	d.identities[identityKey(p, s.Identity)] = id
+
+	old := s.Packet
+	s.update(p, now, dg, d.deletionAuthorization)
+	s.version = desc.version
+	s.End = desc.end
+
+	return []Event{{
+		Type:    EventTypeUpdated,
+		Session: *s,
+		Old:     old,
+		New:     p,
+	}}
+}
+
+func (d *Directory) remove(id string, s *Session) {
+	delete(d.sessions, id)
+
+	key := identityKey(s.Packet, s.Identity)
+
+	if d.identities[key] == id {
+		delete(d.identities, key)
+	}
+}
+
+// Expire removes all sessions that timed out and returns them.
+func (d *Directory) Expire() []Session {
+	d.dispatchMutex.Lock()
+	defer d.dispatchMutex.Unlock()
+
+	expired := d.expire()
+
+	events := make([]Event, 0, len(expired))
+
+	for _, s := range expired {
+		events = append(events, Event{
+			Type:    EventTypeExpired,
+			Session: s,
+			Old:     s.Packet,
+		})
+	}
+
+	d.dispatch(events)
+
+	return expired
+}
+
+func (d *Directory) expire() []Session {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := d.now()
+	expired := make([]Session, 0)
+
+	for id, s := range d.sessions {
+		if now.After(s.ExpiresAt()) {
+			expired = append(expired, *s)
+			d.remove(id, s)
+		}
+	}
+
+	return expired
+}
+
+// Run expires sessions periodically until ctx is done.
+func (d *Directory) Run(ctx context.Context) error {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-ticker.C:
+			d.Expire()
+		}
+	}
+}
+
+func (d *Directory) Session(id string) (Session, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s, ok := d.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+
+	return *s, true
+}
+
+func (d *Directory) Sessions() []Session {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	sessions := make([]Session, 0, len(d.sessions))
+
+	for _, s := range d.sessions {
+		sessions = append(sessions, *s)
+	}
+
+	return sessions
+}
+
+func (d *Directory) Len() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return len(d.sessions)
+}
@@ -0,0 +1,622 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestDirectory() (*Directory, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	d := NewDirectory()
+	d.now = clock.now
+
+	return d, clock
+}
+
+func testPacket(hash uint16) *Packet {
+	return &Packet{
+		Type:        MessageTypeAnnouncement,
+		IDHash:      hash,
+		Origin:      net.ParseIP("192.168.100.254"),
+		PayloadType: SDPPayloadType,
+		Payload:     []byte("v=0\r\n"),
+	}
+}
+
+func TestDirectory_Handle(t *testing.T) {
+	d, clock := newTestDirectory()
+
+	p := testPacket(0x0001)
+
+	d.Handle(p)
+	clock.advance(30 * time.Second)
+	d.Handle(p)
+
+	s, ok := d.Session(p.UniqueID())
+	if !ok {
+		t.Fatalf("Directory.Session() did not find session")
+	}
+
+	if s.Count != 2 {
+		t.Errorf("Session.Count = %d, want 2", s.Count)
+	}
+
+	if s.Interval != 30*time.Second {
+		t.Errorf("Session.Interval = %v, want %v", s.Interval, 30*time.Second)
+	}
+
+	if d.Len() != 1 {
+		t.Errorf("Directory.Len() = %d, want 1", d.Len())
+	}
+
+	deletion := testPacket(0x0001)
+	deletion.Type = MessageTypeDeletion
+
+	d.Handle(deletion)
+
+	if d.Len() != 0 {
+		t.Errorf("Directory.Len() = %d after deletion, want 0", d.Len())
+	}
+}
+
+func TestDirectory_Expire(t *testing.T) {
+	tests := []struct {
+		name       string
+		interval   time.Duration
+		wait       time.Duration
+		wantExpiry bool
+	}{
+		{
+			name:       "short interval within an hour",
+			interval:   time.Minute,
+			wait:       59 * time.Minute,
+			wantExpiry: false,
+		},
+		{
+			name:       "short interval after an hour",
+			interval:   time.Minute,
+			wait:       61 * time.Minute,
+			wantExpiry: true,
+		},
+		{
+			name:       "long interval within ten intervals",
+			interval:   10 * time.Minute,
+			wait:       99 * time.Minute,
+			wantExpiry: false,
+		},
+		{
+			name:       "long interval after ten intervals",
+			interval:   10 * time.Minute,
+			wait:       101 * time.Minute,
+			wantExpiry: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, clock := newTestDirectory()
+
+			p := testPacket(0x0001)
+
+			d.Handle(p)
+			clock.advance(tt.interval)
+			d.Handle(p)
+			clock.advance(tt.wait)
+
+			expired := d.Expire()
+
+			if (len(expired) == 1) != tt.wantExpiry {
+				t.Errorf("Directory.Expire() = %v, wantExpiry %v", expired, tt.wantExpiry)
+			}
+
+			if (d.Len() == 0) != tt.wantExpiry {
+				t.Errorf("Directory.Len() = %d, wantExpiry %v", d.Len(), tt.wantExpiry)
+			}
+		})
+	}
+}
+
+func TestDirectory_Subscribe(t *testing.T) {
+	d, clock := newTestDirectory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := d.Subscribe(ctx)
+
+	first := testPacket(0x0001)
+	changed := testPacket(0x0001)
+	changed.Payload = []byte("v=0\r\ns=changed\r\n")
+	deletion := testPacket(0x0001)
+	deletion.Type = MessageTypeDeletion
+
+	d.Handle(first)
+	d.Handle(first)
+	d.Handle(changed)
+	d.Handle(deletion)
+
+	d.Handle(first)
+	clock.advance(2 * time.Hour)
+	d.Expire()
+
+	want := []struct {
+		typ EventType
+		old *Packet
+		new *Packet
+	}{
+		{EventTypeAdded, nil, first},
+		{EventTypeUpdated, first, changed},
+		{EventTypeDeleted, changed, deletion},
+		{EventTypeAdded, nil, first},
+		{EventTypeExpired, first, nil},
+	}
+
+	for i, w := range want {
+		e := <-events
+
+		if e.Type != w.typ || e.Old != w.old || e.New != w.new {
+			t.Errorf("event %d = %v (old %p, new %p), want %v (old %p, new %p)",
+				i, e.Type, e.Old, e.New, w.typ, w.old, w.new)
+		}
+	}
+
+	cancel()
+
+	for e := range events {
+		t.Errorf("unexpected event %v", e.Type)
+	}
+}
+
+func TestDirectory_HandleDatagram(t *testing.T) {
+	d, clock := newTestDirectory()
+
+	raw, err := testPacket(0x0001).Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	source := &net.UDPAddr{IP: net.ParseIP("192.168.100.254"), Port: 40000}
+
+	for _, offset := range []time.Duration{0, 45 * time.Second} {
+		p, err := d.HandleDatagram(&Datagram{
+			Data:      raw,
+			Source:    source,
+			IfIndex:   3,
+			Timestamp: clock.t.Add(offset),
+		})
+		if err != nil {
+			t.Fatalf("Directory.HandleDatagram() error = %v", err)
+		}
+
+		if p.IDHash != 0x0001 {
+			t.Errorf("Directory.HandleDatagram() = %04x, want 0001", p.IDHash)
+		}
+	}
+
+	s, ok := d.Session(testPacket(0x0001).UniqueID())
+	if !ok {
+		t.Fatalf("Directory.Session() did not find session")
+	}
+
+	if s.Interval != 45*time.Second {
+		t.Errorf("Session.Interval = %v, want %v", s.Interval, 45*time.Second)
+	}
+
+	if s.Source != source || s.IfIndex != 3 {
+		t.Errorf("Session source = %v/%d, want %v/3", s.Source, s.IfIndex, source)
+	}
+}
+
+func TestDirectory_HandleDatagram_duplicates(t *testing.T) {
+	raw, err := testPacket(0x0001).Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	type arrival struct {
+		offset  time.Duration
+		ifIndex int
+	}
+
+	tests := []struct {
+		name   string
+		copies []arrival
+		count  int
+	}{
+		{"same interface", []arrival{{0, 3}, {2 * time.Millisecond, 3}, {60 * time.Second, 3}, {60*time.Second + 2*time.Millisecond, 3}}, 2},
+		{"other interface", []arrival{{0, 3}, {time.Millisecond, 2}, {60 * time.Second, 3}, {60*time.Second + 5*time.Second, 2}, {120 * time.Second, 3}}, 3},
+		{"interface failover", []arrival{{0, 3}, {60 * time.Second, 3}, {120 * time.Second, 2}, {180 * time.Second, 2}}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, clock := newTestDirectory()
+
+			for _, c := range tt.copies {
+				if _, err := d.HandleDatagram(&Datagram{
+					Data:      raw,
+					IfIndex:   c.ifIndex,
+					Timestamp: clock.t.Add(c.offset),
+				}); err != nil {
+					t.Fatalf("Directory.HandleDatagram() error = %v", err)
+				}
+			}
+
+			s, ok := d.Session(testPacket(0x0001).UniqueID())
+			if !ok {
+				t.Fatalf("Directory.Session() did not find session")
+			}
+
+			if s.Count != tt.count || s.Interval != 60*time.Second {
+				t.Errorf("Session.Count, Interval = %d, %v, want %d, %v", s.Count, s.Interval, tt.count, 60*time.Second)
+			}
+		})
+	}
+}
+
+func TestDirectory_deletionAuthorization(t *testing.T) {
+	announcement, err := testPacket(0x0001).Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	deletion := testPacket(0x0001)
+	deletion.Type = MessageTypeDeletion
+
+	raw, err := deletion.Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	announcer := &net.UDPAddr{IP: net.ParseIP("192.168.100.254"), Port: 40000}
+	spoofer := &net.UDPAddr{IP: net.ParseIP("192.168.100.66"), Port: 40000}
+
+	tests := []struct {
+		name         string
+		authorize    bool
+		signer       string
+		deletion     *Datagram
+		wantRejected bool
+	}{
+		{
+			name:     "no policy",
+			deletion: &Datagram{Data: raw, Source: spoofer},
+		},
+		{
+			name:      "same source",
+			authorize: true,
+			deletion:  &Datagram{Data: raw, Source: &net.UDPAddr{IP: announcer.IP, Port: 40001}},
+		},
+		{
+			name:         "other source",
+			authorize:    true,
+			deletion:     &Datagram{Data: raw, Source: spoofer},
+			wantRejected: true,
+		},
+		{
+			name:      "same signer",
+			authorize: true,
+			signer:    "a",
+			deletion:  &Datagram{Data: raw, Source: announcer, Signer: "a"},
+		},
+		{
+			name:         "other signer",
+			authorize:    true,
+			signer:       "a",
+			deletion:     &Datagram{Data: raw, Source: announcer, Signer: "b"},
+			wantRejected: true,
+		},
+		{
+			name:         "unsigned",
+			authorize:    true,
+			signer:       "a",
+			deletion:     &Datagram{Data: raw, Source: announcer},
+			wantRejected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []DirectoryOption
+			if tt.authorize {
+				opts = append(opts, WithDeletionAuthorization())
+			}
+
+			d := NewDirectory(opts...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := d.Subscribe(ctx)
+
+			if _, err := d.HandleDatagram(&Datagram{Data: announcement, Source: announcer, Signer: tt.signer}); err != nil {
+				t.Fatalf("Directory.HandleDatagram() error = %v", err)
+			}
+
+			<-events
+
+			if _, err := d.HandleDatagram(tt.deletion); err != nil {
+				t.Fatalf("Directory.HandleDatagram() error = %v", err)
+			}
+
+			e := <-events
+
+			if tt.wantRejected {
+				if e.Type != EventTypeDeletionRejected || e.Datagram != tt.deletion || !errors.Is(e.Err, ErrDeletionUnauthorized) {
+					t.Errorf("event = %v (datagram %p, err %v), want %v", e.Type, e.Datagram, e.Err, EventTypeDeletionRejected)
+				}
+
+				if d.Len() != 1 {
+					t.Errorf("Directory.Len() = %d, want 1", d.Len())
+				}
+			} else {
+				if e.Type != EventTypeDeleted {
+					t.Errorf("event = %v, want %v", e.Type, EventTypeDeleted)
+				}
+
+				if d.Len() != 0 {
+					t.Errorf("Directory.Len() = %d, want 0", d.Len())
+				}
+			}
+		})
+	}
+
+	d := NewDirectory(WithDeletionAuthorization())
+	d.Handle(testPacket(0x0001))
+	d.Handle(deletion)
+
+	if d.Len() != 1 {
+		t.Errorf("Directory.Len() = %d after Handle() of a deletion, want 1", d.Len())
+	}
+}
+
+func TestDirectory_deletionAuthorization_spoofing(t *testing.T) {
+	encode := func(p *Packet) []byte {
+		raw, err := p.Encode()
+		if err != nil {
+			t.Fatalf("Packet.Encode() error = %v", err)
+		}
+
+		return raw
+	}
+
+	deletion := func(p *Packet) *Packet {
+		p.Type = MessageTypeDeletion
+
+		return p
+	}
+
+	announcer := &net.UDPAddr{IP: net.ParseIP("192.168.100.254"), Port: 40000}
+	spoofer := &net.UDPAddr{IP: net.ParseIP("10.6.6.6"), Port: 40000}
+
+	tests := []struct {
+		name  string
+		spoof []*Datagram
+		want  []EventType
+	}{
+		{
+			name: "unsigned refresh",
+			spoof: []*Datagram{
+				{Data: encode(versionedTestPacket(0x0001, 1)), Source: spoofer},
+				{Data: encode(deletion(versionedTestPacket(0x0001, 1))), Source: spoofer},
+			},
+			want: []EventType{EventTypeAnnouncementRejected, EventTypeDeletionRejected},
+		},
+		{
+			name: "new version",
+			spoof: []*Datagram{
+				{Data: encode(versionedTestPacket(0x0002, 2)), Source: spoofer, Signer: "legit"},
+				{Data: encode(deletion(versionedTestPacket(0x0002, 2))), Source: spoofer, Signer: "legit"},
+				{Data: encode(deletion(versionedTestPacket(0x0001, 1))), Source: spoofer, Signer: "legit"},
+			},
+			want: []EventType{EventTypeAnnouncementRejected, EventTypeDeletionRejected},
+		},
+		{
+			name: "same host, unsigned",
+			spoof: []*Datagram{
+				{Data: encode(versionedTestPacket(0x0002, 2)), Source: announcer},
+				{Data: encode(deletion(versionedTestPacket(0x0001, 1))), Source: announcer},
+			},
+			want: []EventType{EventTypeAnnouncementRejected, EventTypeDeletionRejected},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDirectory(WithDeletionAuthorization())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := d.Subscribe(ctx)
+
+			first := &Datagram{Data: encode(versionedTestPacket(0x0001, 1)), Source: announcer, Signer: "legit"}
+
+			for _, dg := range append([]*Datagram{first}, tt.spoof...) {
+				if _, err := d.HandleDatagram(dg); err != nil {
+					t.Fatalf("Directory.HandleDatagram() error = %v", err)
+				}
+			}
+
+			if e := <-events; e.Type != EventTypeAdded {
+				t.Fatalf("event = %v, want %v", e.Type, EventTypeAdded)
+			}
+
+			for _, want := range tt.want {
+				if e := <-events; e.Type != want || e.Datagram == nil {
+					t.Errorf("event = %v (datagram %p), want %v", e.Type, e.Datagram, want)
+				}
+			}
+
+			s, ok := d.Session(versionedTestPacket(0x0001, 1).UniqueID())
+			if !ok {
+				t.Fatalf("Directory.Session() did not find session")
+			}
+
+			if s.Source != announcer || s.Signer != "legit" {
+				t.Errorf("Session source = %v/%q, want %v/%q", s.Source, s.Signer, announcer, "legit")
+			}
+
+			if d.Len() != 1 {
+				t.Errorf("Directory.Len() = %d, want 1", d.Len())
+			}
+		})
+	}
+
+	d := NewDirectory(WithDeletionAuthorization())
+
+	for _, dg := range []*Datagram{
+		{Data: encode(versionedTestPacket(0x0001, 1)), Source: announcer, Signer: "legit"},
+		{Data: encode(versionedTestPacket(0x0002, 2)), Source: &net.UDPAddr{IP: announcer.IP, Port: 40001}, Signer: "legit"},
+	} {
+		if _, err := d.HandleDatagram(dg); err != nil {
+			t.Fatalf("Directory.HandleDatagram() error = %v", err)
+		}
+	}
+
+	s, ok := d.Session(versionedTestPacket(0x0002, 2).UniqueID())
+	if !ok || s.Source != announcer {
+		t.Errorf("Directory.Session() = %v, %v, want update keeping source %v", s.Source, ok, announcer)
+	}
+}
+
+func versionedTestPacket(hash uint16, version int) *Packet {
+	p := testPacket(hash)
+	p.Payload = []byte(fmt.Sprintf("v=0\r\n"+
+		"o=- 1234 %d IN IP4 192.168.100.254\r\n"+
+		"s=test\r\n"+
+		"t=0 0\r\n", version))
+
+	return p
+}
+
+func TestDirectory_Handle_identityOrigin(t *testing.T) {
+	d, _ := newTestDirectory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := d.Subscribe(ctx)
+
+	withOrigin := func(p *Packet, origin string) *Packet {
+		p.Origin = net.ParseIP(origin)
+
+		return p
+	}
+
+	// Same o= line from two SAP origins
+	a := withOrigin(versionedTestPacket(0x0001, 1), "10.0.0.1")
+	b := withOrigin(versionedTestPacket(0x0002, 1), "10.1.0.1")
+	bv2 := withOrigin(versionedTestPacket(0x0003, 2), "10.1.0.1")
+
+	d.Handle(a)
+	d.Handle(b)
+	d.Handle(bv2)
+
+	want := []struct {
+		typ EventType
+		old *Packet
+		new *Packet
+	}{
+		{EventTypeAdded, nil, a},
+		{EventTypeAdded, nil, b},
+		{EventTypeUpdated, b, bv2},
+	}
+
+	for i, w := range want {
+		e := <-events
+
+		if e.Type != w.typ || e.Old != w.old || e.New != w.new {
+			t.Errorf("event %d = %v (old %p, new %p), want %v (old %p, new %p)",
+				i, e.Type, e.Old, e.New, w.typ, w.old, w.new)
+		}
+	}
+
+	if _, ok := d.Session(a.UniqueID()); !ok || d.Len() != 2 {
+		t.Errorf("Directory.Len() = %d, want both origins", d.Len())
+	}
+
+	// A sender that restarts with a new hash but the same version
+	restarted := withOrigin(versionedTestPacket(0x0004, 1), "10.0.0.1")
+	d.Handle(restarted)
+
+	if e := <-events; e.Type != EventTypeUpdated || e.Old != a || e.New != restarted {
+		t.Errorf("event = %v (old %p, new %p), want %v from the previous hash", e.Type, e.Old, e.New, EventTypeUpdated)
+	}
+
+	if _, ok := d.Session(restarted.UniqueID()); !ok || d.Len() != 2 {
+		t.Errorf("Directory.Session() did not move to the new hash, Len() = %d", d.Len())
+	}
+}
+
+func TestDirectory_Handle_identity(t *testing.T) {
+	d, _ := newTestDirectory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := d.Subscribe(ctx)
+
+	v1 := versionedTestPacket(0x0001, 1)
+	v2 := versionedTestPacket(0x0002, 2)
+
+	d.Handle(v1)
+	d.Handle(v2)
+
+	if e := <-events; e.Type != EventTypeAdded {
+		t.Fatalf("first event = %v, want %v", e.Type, EventTypeAdded)
+	}
+
+	e := <-events
+	if e.Type != EventTypeUpdated || e.Old != v1 || e.New != v2 {
+		t.Fatalf("second event = %v, want %v from v1 to v2", e.Type, EventTypeUpdated)
+	}
+
+	if e.Session.Identity != "- 1234 IN IP4 192.168.100.254" || e.Session.Count != 2 {
+		t.Errorf("Session = %+v, want identity of the origin and count 2", e.Session)
+	}
+
+	if _, ok := d.Session(v1.UniqueID()); ok {
+		t.Errorf("Directory.Session() found the retired hash")
+	}
+
+	if _, ok := d.Session(v2.UniqueID()); !ok {
+		t.Errorf("Directory.Session() did not find the new hash")
+	}
+
+	// A late announcement of the previous version is ignored.
+	d.Handle(versionedTestPacket(0x0001, 1))
+
+	if d.Len() != 1 {
+		t.Errorf("Directory.Len() = %d after stale announcement, want 1", d.Len())
+	}
+
+	deletion := versionedTestPacket(0x0002, 2)
+	deletion.Type = MessageTypeDeletion
+
+	d.Handle(deletion)
+
+	if e := <-events; e.Type != EventTypeDeleted {
+		t.Fatalf("third event = %v, want %v", e.Type, EventTypeDeleted)
+	}
+
+	// Once deleted, the identity is free for a new session.
+	d.Handle(versionedTestPacket(0x0003, 1))
+
+	if e := <-events; e.Type != EventTypeAdded {
+		t.Errorf("fourth event = %v, want %v", e.Type, EventTypeAdded)
+	}
+}
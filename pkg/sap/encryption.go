@@ -0,0 +1,177 @@
+package sap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const encryptionPaddingFlag = uint8(1 << 7)
+
+var ErrNoKey = errors.New("no key for encrypted packet")
+var ErrInvalidEncryptedPayload = errors.New("invalid encrypted payload")
+
+// Key encrypts and decrypts the payloads of encrypted packets. Plaintexts are
+// padded to a multiple of the block size as RFC 2974, section 7 describes,
+// which ciphers that need no padding avoid by returning 1.
+type Key interface {
+	BlockSize() int
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// Keyring looks up the key for the key ID of an encrypted packet.
+type Keyring interface {
+	Key(id uint32) (Key, bool)
+}
+
+// Keys is a Keyring with a fixed set of keys.
+type Keys map[uint32]Key
+
+func (k Keys) Key(id uint32) (Key, bool) {
+	key, ok := k[id]
+
+	return key, ok
+}
+
+func lookupKey(k Keyring, id uint32) (Key, bool) {
+	if k == nil {
+		return nil, false
+	}
+
+	return k.Key(id)
+}
+
+type aesKey struct {
+	aead cipher.AEAD
+}
+
+// NewAESKey returns a key that encrypts with AES-GCM. The secret must be 16,
+// 24 or 32 bytes long. Each ciphertext is prefixed with its random nonce.
+func NewAESKey(secret []byte) (Key, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesKey{aead: aead}, nil
+}
+
+// ParseAESKey parses a key given as <key-id>:<secret>, both in hex, and
+// returns the key ID along with the AES-GCM key for the secret.
+func ParseAESKey(s string) (uint32, Key, error) {
+	id, secret, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, nil, errors.Errorf("key %q is not <key-id>:<secret>", s)
+	}
+
+	keyID, err := strconv.ParseUint(id, 16, 32)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "key ID")
+	}
+
+	b, err := hex.DecodeString(secret)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "secret")
+	}
+
+	key, err := NewAESKey(b)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return uint32(keyID), key, nil
+}
+
+func (k *aesKey) BlockSize() int {
+	return 1
+}
+
+func (k *aesKey) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(plaintext)+k.aead.Overhead())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return k.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (k *aesKey) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < k.aead.NonceSize() {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	nonce, sealed := ciphertext[:k.aead.NonceSize()], ciphertext[k.aead.NonceSize():]
+
+	plaintext, err := k.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidEncryptedPayload, "%v", err)
+	}
+
+	return plaintext, nil
+}
+
+// RFC 2974, section 7:
+// | P |  random (31 bits)  | payload ... | padding ... | padding count |
+// The whole is encrypted. If the padding bit is set, the last byte holds the
+// number of padding bytes including itself.
+func encryptPayload(key Key, payload []byte) ([]byte, error) {
+	plaintext := make([]byte, 4, 4+len(payload)+key.BlockSize())
+
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, err
+	}
+
+	plaintext[0] &^= encryptionPaddingFlag
+	plaintext = append(plaintext, payload...)
+
+	if bs := key.BlockSize(); bs > 1 && len(plaintext)%bs != 0 {
+		padding := bs - len(plaintext)%bs
+
+		plaintext[0] |= encryptionPaddingFlag
+		plaintext = append(plaintext, make([]byte, padding-1)...)
+		plaintext = append(plaintext, uint8(padding))
+	}
+
+	return key.Encrypt(plaintext)
+}
+
+func decryptPayload(key Key, ciphertext []byte) ([]byte, error) {
+	plaintext, err := key.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plaintext) < 4 {
+		return nil, ErrInvalidEncryptedPayload
+	}
+
+	payload := plaintext[4:]
+
+	if plaintext[0]&encryptionPaddingFlag != 0 {
+		if len(payload) == 0 {
+			return nil, errors.Wrap(ErrInvalidEncryptedPayload, "missing padding")
+		}
+
+		padding := int(payload[len(payload)-1])
+
+		if padding == 0 || padding > len(payload) {
+			return nil, errors.Wrapf(ErrInvalidEncryptedPayload, "%d padding bytes", padding)
+		}
+
+		payload = payload[:len(payload)-padding]
+	}
+
+	return payload, nil
+}
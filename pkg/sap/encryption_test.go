@@ -0,0 +1,204 @@
+package sap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// blockTestKey is a toy block cipher that requires padding.
+type blockTestKey struct {
+	blockSize int
+}
+
+func (k *blockTestKey) BlockSize() int {
+	return k.blockSize
+}
+
+func (k *blockTestKey) Encrypt(plaintext []byte) ([]byte, error) {
+	if len(plaintext)%k.blockSize != 0 {
+		return nil, errors.New("plaintext not padded")
+	}
+
+	ciphertext := make([]byte, len(plaintext))
+	for i, b := range plaintext {
+		ciphertext[i] = b ^ 0x5a
+	}
+
+	return ciphertext, nil
+}
+
+func (k *blockTestKey) Decrypt(ciphertext []byte) ([]byte, error) {
+	return k.Encrypt(ciphertext)
+}
+
+func testAESKey(t *testing.T, secret string) Key {
+	key, err := NewAESKey([]byte(secret))
+	if err != nil {
+		t.Fatalf("NewAESKey() error = %v", err)
+	}
+
+	return key
+}
+
+func TestParseAESKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    uint32
+		wantErr bool
+	}{
+		{"valid", "2342:000102030405060708090a0b0c0d0e0f", 0x2342, false},
+		{"no key ID", "000102030405060708090a0b0c0d0e0f", 0, true},
+		{"key ID too long", "123456789:000102030405060708090a0b0c0d0e0f", 0, true},
+		{"secret not hex", "1:secret", 0, true},
+		{"secret too short", "1:0001", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, key, err := ParseAESKey(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAESKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want || (key == nil) != tt.wantErr {
+				t.Errorf("ParseAESKey() = %08x, %v, want %08x", got, key, tt.want)
+			}
+		})
+	}
+}
+
+func TestPacket_encryption(t *testing.T) {
+	timeout := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name       string
+		key        Key
+		compressed bool
+	}{
+		{"aes", testAESKey(t, "0123456789abcdef"), false},
+		{"aes compressed", testAESKey(t, "0123456789abcdef"), true},
+		{"padded", &blockTestKey{blockSize: 16}, false},
+		{"padded compressed", &blockTestKey{blockSize: 16}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPacket(0x0001)
+			p.Compressed = tt.compressed
+			p.Encrypted = true
+			p.KeyID = 0x01020304
+			p.Timeout = timeout
+
+			keyring := Keys{p.KeyID: tt.key}
+
+			raw, err := p.EncodeWithKeyring(keyring)
+			if err != nil {
+				t.Fatalf("Packet.Encode() error = %v", err)
+			}
+
+			if got := binary.BigEndian.Uint32(raw[8:]); got != p.KeyID {
+				t.Errorf("key ID = %08x, want %08x", got, p.KeyID)
+			}
+
+			if bytes.Contains(raw, []byte(p.PayloadType)) {
+				t.Errorf("Packet.Encode() contains plaintext")
+			}
+
+			back, err := DecodePacketWithKeyring(raw, keyring)
+			if err != nil {
+				t.Fatalf("DecodePacket() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(p, back) {
+				t.Errorf("DecodePacket() = %v, want %v", back, p)
+			}
+		})
+	}
+}
+
+func TestPacket_encryption_noKey(t *testing.T) {
+	p := testPacket(0x0001)
+	p.Encrypted = true
+	p.KeyID = 7
+	p.Timeout = time.Unix(1710000000, 0)
+
+	if _, err := p.Encode(); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("Packet.Encode() error = %v, want %v", err, ErrNoKey)
+	}
+
+	raw, err := p.EncodeWithKeyring(Keys{7: testAESKey(t, "0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	back, err := DecodePacketWithKeyring(raw, Keys{8: testAESKey(t, "0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("DecodePacket() error = %v", err)
+	}
+
+	if back.KeyID != p.KeyID || !back.Timeout.Equal(p.Timeout) {
+		t.Errorf("DecodePacket() key ID, timeout = %d, %v, want %d, %v", back.KeyID, back.Timeout, p.KeyID, p.Timeout)
+	}
+
+	if back.PayloadType != "" || !bytes.Equal(back.Payload, raw[16:]) {
+		t.Errorf("DecodePacket() payload = %q %x, want opaque %x", back.PayloadType, back.Payload, raw[16:])
+	}
+
+	if _, err := DecodePacketWithKeyring(raw, Keys{7: testAESKey(t, "fedcba9876543210")}); !errors.Is(err, ErrInvalidEncryptedPayload) {
+		t.Errorf("DecodePacketWithKeyring() error = %v, want %v", err, ErrInvalidEncryptedPayload)
+	}
+
+	d, _ := newTestDirectory()
+
+	if _, err := d.HandleDatagram(&Datagram{Data: raw}); err != nil {
+		t.Fatalf("Directory.HandleDatagram() error = %v", err)
+	}
+
+	s, ok := d.Session(p.UniqueID())
+	if !ok || !s.End.Equal(p.Timeout) {
+		t.Errorf("Directory.Session() = %v, %v, want end at timeout %v", s.End, ok, p.Timeout)
+	}
+}
+
+func TestAnnouncer_encryption(t *testing.T) {
+	keyring := Keys{1: testAESKey(t, "0123456789abcdef")}
+	authenticator := &HMACAuthenticator{
+		KeyID: "test",
+		Keys:  map[string][]byte{"test": []byte("secret")},
+	}
+
+	c := newConfig([]Option{WithKeyring(keyring), WithAuthenticator(authenticator)})
+
+	p := testPacket(0x0001)
+	p.Encrypted = true
+	p.KeyID = 1
+
+	raw, err := c.encode(p)
+	if err != nil {
+		t.Fatalf("config.encode() error = %v", err)
+	}
+
+	if len(p.AuthenticationData) != 0 {
+		t.Errorf("config.encode() modified the packet")
+	}
+
+	if _, err := VerifyPacket(raw, authenticator); err != nil {
+		t.Fatalf("VerifyPacket() error = %v", err)
+	}
+
+	back, err := DecodePacketWithKeyring(raw, keyring)
+	if err != nil {
+		t.Fatalf("DecodePacketWithKeyring() error = %v", err)
+	}
+
+	if !bytes.Equal(back.Payload, p.Payload) {
+		t.Errorf("DecodePacket() payload = %q, want %q", back.Payload, p.Payload)
+	}
+
+	if err := p.Sign(authenticator); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Packet.Sign() error = %v, want %v", err, ErrNoKey)
+	}
+}
@@ -0,0 +1,68 @@
+package sap
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHMACAuthenticator(t *testing.T) {
+	a := &HMACAuthenticator{
+		KeyID: "2024",
+		Keys: map[string][]byte{
+			"2023": []byte("old secret"),
+			"2024": []byte("new secret"),
+		},
+	}
+
+	p := testPacket(0x0001)
+
+	if err := p.Sign(a); err != nil {
+		t.Fatalf("Packet.Sign() error = %v", err)
+	}
+
+	raw, err := p.Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		keys    map[string][]byte
+		want    string
+		wantErr error
+	}{
+		{
+			name: "known key",
+			keys: a.Keys,
+			want: "2024",
+		},
+		{
+			name:    "unknown key",
+			keys:    map[string][]byte{"2023": []byte("old secret")},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "wrong secret",
+			keys:    map[string][]byte{"2024": []byte("other secret")},
+			wantErr: ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyPacket(raw, &HMACAuthenticator{Keys: tt.keys})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyPacket() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("VerifyPacket() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	a.KeyID = "2025"
+
+	if err := p.Sign(a); err == nil {
+		t.Errorf("Packet.Sign() with unknown key succeeded")
+	}
+}
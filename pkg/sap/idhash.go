@@ -0,0 +1,113 @@
+package sap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+)
+
+// idHashAttempts bounds the search for a hash that does not collide. With
+// 65535 possible values this is only reached if the origin announces an
+// unreasonable number of sessions.
+const idHashAttempts = 256
+
+var ErrNoIDHash = errors.New("no free message ID hash")
+
+// PayloadIDHash derives a message ID hash from the payload type and payload
+// of p. It stays the same as long as they do, and changes when the session is
+// modified, as RFC 2974, section 5 requires.
+func PayloadIDHash(p *Packet) uint16 {
+	h, _ := payloadIDHash(p, func(uint16) bool { return false })
+
+	return h
+}
+
+// payloadIDHash returns the first hash derived from the payload for which
+// taken returns false. Zero is never returned, as earlier versions of SAP use
+// it to mean that the hash should be ignored.
+func payloadIDHash(p *Packet, taken func(hash uint16) bool) (uint16, bool) {
+	for attempt := uint32(0); attempt < idHashAttempts; attempt++ {
+		f := fnv.New32a()
+		f.Write([]byte(p.PayloadType))
+		f.Write([]byte{0})
+		f.Write(p.Payload)
+
+		if attempt > 0 {
+			binary.Write(f, binary.BigEndian, attempt)
+		}
+
+		sum := f.Sum32()
+		h := uint16(sum>>16) ^ uint16(sum)
+
+		if h != 0 && !taken(h) {
+			return h, true
+		}
+	}
+
+	return 0, false
+}
+
+// idHashCollides reports whether other is a different session that uses the
+// same origin and message ID hash as p. Identical announcements are the same
+// session, for example one this process is announcing and hears back.
+func idHashCollides(p *Packet, hash uint16, other *Packet) bool {
+	return other.IDHash == hash &&
+		other.Origin.Equal(p.Origin) &&
+		(other.PayloadType != p.PayloadType || !bytes.Equal(other.Payload, p.Payload))
+}
+
+func directoryCollides(d *Directory, p *Packet, hash uint16) bool {
+	for _, s := range d.Sessions() {
+		if idHashCollides(p, hash, s.Packet) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveIDHash derives the message ID hash of p from its payload if
+// WithPayloadIDHash is set. taken reports collisions with sessions known to
+// the caller besides those of the configured directory.
+func (c *config) resolveIDHash(p *Packet, taken func(hash uint16) bool) error {
+	if !c.payloadIDHash {
+		return nil
+	}
+
+	h, ok := payloadIDHash(p, func(hash uint16) bool {
+		if c.directory != nil && directoryCollides(c.directory, p, hash) {
+			return true
+		}
+
+		return taken != nil && taken(hash)
+	})
+	if !ok {
+		return ErrNoIDHash
+	}
+
+	p.IDHash = h
+
+	return nil
+}
+
+// idHashTaken checks a hash for p against the announcer's own announcements
+// except skip and the sessions heard on its groups.
+func (a *Announcer) idHashTaken(p *Packet, skip *Announcement) func(hash uint16) bool {
+	return func(hash uint16) bool {
+		for _, g := range a.groups {
+			for _, an := range g.announcements {
+				if an != skip && idHashCollides(p, hash, &an.packet) {
+					return true
+				}
+			}
+
+			if g.traffic != nil && directoryCollides(g.traffic.directory, p, hash) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
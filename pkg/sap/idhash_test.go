@@ -0,0 +1,70 @@
+package sap
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPayloadIDHash(t *testing.T) {
+	p := testPacket(0)
+	h := PayloadIDHash(p)
+
+	if h == 0 {
+		t.Fatalf("PayloadIDHash() = 0")
+	}
+
+	if again := PayloadIDHash(testPacket(0x1234)); again != h {
+		t.Errorf("PayloadIDHash() = %04x for the same payload, want %04x", again, h)
+	}
+
+	p.Payload = []byte("v=0\r\ns=updated\r\n")
+
+	if changed := PayloadIDHash(p); changed == h {
+		t.Errorf("PayloadIDHash() = %04x for a different payload, want another hash", changed)
+	}
+}
+
+func TestAnnouncer_payloadIDHash(t *testing.T) {
+	p := testPacket(0)
+	h := PayloadIDHash(p)
+
+	// A different session from the same origin already uses the hash.
+	other := testPacket(h)
+	other.Payload = []byte("v=0\r\ns=other\r\n")
+
+	d := NewDirectory()
+	d.Handle(other)
+
+	a := NewAnnouncer(WithPayloadIDHash(), WithDirectory(d))
+	defer a.close()
+
+	ip := net.IPv4(127, 0, 0, 1)
+
+	an, err := a.Add(ip, p)
+	if err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	got := an.Packet().IDHash
+	if got == h || got == 0 {
+		t.Errorf("Packet.IDHash = %04x, want a hash other than %04x", got, h)
+	}
+
+	// The hash is stable for the same payload and origin.
+	second, err := a.Add(ip, p)
+	if err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	if second.Packet().IDHash != got {
+		t.Errorf("Packet.IDHash = %04x, want %04x", second.Packet().IDHash, got)
+	}
+
+	if err := an.Update([]byte("v=0\r\ns=updated\r\n")); err != nil {
+		t.Fatalf("Announcement.Update() error = %v", err)
+	}
+
+	if an.Packet().IDHash == got {
+		t.Errorf("Packet.IDHash = %04x after update, want a new hash", got)
+	}
+}
@@ -2,19 +2,84 @@ package sap
 
 import (
 	"net"
+	"time"
 )
 
+// Datagram is a raw SAP packet along with the metadata of its reception.
+// Signer identifies the key the packet was signed with if the listener
+// verifies signatures, see WithVerification.
+type Datagram struct {
+	Data      []byte
+	Source    *net.UDPAddr
+	IfIndex   int
+	Group     net.IP
+	Timestamp time.Time
+	Signer    string
+}
+
+// verify checks the signature of the datagram if an authenticator is given.
+func (d *Datagram) verify(a Authenticator) error {
+	if a == nil {
+		return nil
+	}
+
+	signer, err := VerifyPacket(d.Data, a)
+	if err != nil {
+		return err
+	}
+
+	d.Signer = signer
+
+	return nil
+}
+
+// ListenerOption configures a Listener or MultiListener.
+type ListenerOption func(c *listenerConfig)
+
+type listenerConfig struct {
+	authenticator Authenticator
+}
+
+// WithVerification makes listeners verify the signatures of all received
+// packets with a.
+func WithVerification(a Authenticator) ListenerOption {
+	return func(c *listenerConfig) {
+		c.authenticator = a
+	}
+}
+
+func newListenerConfig(opts []ListenerOption) listenerConfig {
+	var c listenerConfig
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
 type Listener struct {
-	conn *net.UDPConn
+	conn          *net.UDPConn
+	ip            net.IP
+	ifi           *net.Interface
+	authenticator Authenticator
 }
 
-func NewListener(ip net.IP, ifi *net.Interface) (*Listener, error) {
+// NewListener joins the group ip on the interface ifi, or the one chosen by
+// the system if ifi is nil.
+func NewListener(ip net.IP, ifi *net.Interface, opts ...ListenerOption) (*Listener, error) {
+	c := newListenerConfig(opts)
+
 	udpAddr := &net.UDPAddr{
 		IP:   ip,
 		Port: sapPort,
 	}
 
-	conn, err := net.ListenMulticastUDP("udp4", ifi, udpAddr)
+	if ifi != nil && ip.IsLinkLocalMulticast() {
+		udpAddr.Zone = ifi.Name
+	}
+
+	conn, err := net.ListenMulticastUDP(udpNetwork(ip), ifi, udpAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -23,8 +88,17 @@ func NewListener(ip net.IP, ifi *net.Interface) (*Listener, error) {
 		return nil, err
 	}
 
+	if err := enableControlMessages(conn, ip.To4() == nil); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
 	return &Listener{
-		conn: conn,
+		conn:          conn,
+		ip:            ip,
+		ifi:           ifi,
+		authenticator: c.authenticator,
 	}, nil
 }
 
@@ -32,13 +106,62 @@ func (l *Listener) Close() {
 	l.conn.Close()
 }
 
-func (l *Listener) ReadPacketRaw() ([]byte, error) {
+// ReadDatagram reads the next packet along with its source address, the index
+// of the interface it was received on, the group it was sent to and the time
+// of reception. Where the platform supports it, the interface, group and
+// timestamp are provided by the kernel.
+//
+// With WithVerification, packets that fail verification are returned along
+// with an *AuthenticationError. Reading can continue after such errors.
+func (l *Listener) ReadDatagram() (*Datagram, error) {
+	d, err := readDatagram(l.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Group == nil {
+		d.Group = l.ip
+	}
+
+	if d.IfIndex == 0 && l.ifi != nil {
+		d.IfIndex = l.ifi.Index
+	}
+
+	if err := d.verify(l.authenticator); err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+func readDatagram(conn *net.UDPConn) (*Datagram, error) {
 	buf := make([]byte, maxDatagramSize)
+	oob := make([]byte, controlMessageSize)
+
+	n, oobn, _, src, err := conn.ReadMsgUDP(buf, oob)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Datagram{
+		Data:   buf[:n],
+		Source: src,
+	}
 
-	n, _, err := l.conn.ReadFromUDP(buf)
+	parseControlMessages(oob[:oobn], d)
+
+	if d.Timestamp.IsZero() {
+		d.Timestamp = time.Now()
+	}
+
+	return d, nil
+}
+
+func (l *Listener) ReadPacketRaw() ([]byte, error) {
+	d, err := l.ReadDatagram()
 	if err != nil {
 		return nil, err
 	}
 
-	return buf[:n], nil
+	return d.Data, nil
 }
@@ -0,0 +1,78 @@
+package sap
+
+import (
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+var sizeofTimeval = int(unsafe.Sizeof(unix.Timeval{}))
+
+var controlMessageSize = unix.CmsgSpace(sizeofTimeval) +
+	unix.CmsgSpace(unix.SizeofInet6Pktinfo)
+
+func enableControlMessages(conn *net.UDPConn, ipv6 bool) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+
+	err = rc.Control(func(fd uintptr) {
+		if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMP, 1); sockErr != nil {
+			return
+		}
+
+		if ipv6 {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_RECVPKTINFO, 1)
+		} else {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_PKTINFO, 1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
+
+func parseControlMessages(oob []byte, d *Datagram) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return
+	}
+
+	for _, m := range msgs {
+		switch {
+		case m.Header.Level == unix.SOL_SOCKET && m.Header.Type == unix.SCM_TIMESTAMP:
+			if len(m.Data) < sizeofTimeval {
+				continue
+			}
+
+			tv := (*unix.Timeval)(unsafe.Pointer(&m.Data[0]))
+			d.Timestamp = time.Unix(tv.Unix())
+
+		case m.Header.Level == unix.IPPROTO_IP && m.Header.Type == unix.IP_PKTINFO:
+			if len(m.Data) < unix.SizeofInet4Pktinfo {
+				continue
+			}
+
+			info := (*unix.Inet4Pktinfo)(unsafe.Pointer(&m.Data[0]))
+			d.IfIndex = int(info.Ifindex)
+			d.Group = net.IPv4(info.Addr[0], info.Addr[1], info.Addr[2], info.Addr[3])
+
+		case m.Header.Level == unix.IPPROTO_IPV6 && m.Header.Type == unix.IPV6_PKTINFO:
+			if len(m.Data) < unix.SizeofInet6Pktinfo {
+				continue
+			}
+
+			info := (*unix.Inet6Pktinfo)(unsafe.Pointer(&m.Data[0]))
+			d.IfIndex = int(info.Ifindex)
+			d.Group = make(net.IP, net.IPv6len)
+			copy(d.Group, info.Addr[:])
+		}
+	}
+}
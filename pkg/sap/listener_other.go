@@ -0,0 +1,16 @@
+//go:build !linux
+
+package sap
+
+import (
+	"net"
+)
+
+const controlMessageSize = 0
+
+func enableControlMessages(conn *net.UDPConn, ipv6 bool) error {
+	return nil
+}
+
+func parseControlMessages(oob []byte, d *Datagram) {
+}
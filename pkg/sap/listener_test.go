@@ -0,0 +1,216 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestListener_ReadDatagram(t *testing.T) {
+	l, err := NewListener(IPv4AdminLocalGroup, nil)
+	if err != nil {
+		t.Skipf("cannot listen on multicast group: %v", err)
+	}
+
+	defer l.Close()
+
+	conn, err := dialGroup(IPv4AdminLocalGroup, &config{})
+	if err != nil {
+		t.Skipf("cannot send to multicast group: %v", err)
+	}
+
+	defer conn.Close()
+
+	raw, err := testPacket(0x0001).Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	before := time.Now()
+
+	if _, err := conn.Write(raw); err != nil {
+		t.Skipf("cannot send to multicast group: %v", err)
+	}
+
+	l.conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	d, err := l.ReadDatagram()
+	if err != nil {
+		t.Skipf("no multicast loopback: %v", err)
+	}
+
+	if string(d.Data) != string(raw) {
+		t.Errorf("Datagram.Data = %v, want %v", d.Data, raw)
+	}
+
+	local := conn.LocalAddr().(*net.UDPAddr)
+
+	if d.Source.Port != local.Port {
+		t.Errorf("Datagram.Source = %v, want port %d", d.Source, local.Port)
+	}
+
+	if !d.Group.Equal(IPv4AdminLocalGroup) {
+		t.Errorf("Datagram.Group = %v, want %v", d.Group, IPv4AdminLocalGroup)
+	}
+
+	if d.Timestamp.Before(before.Add(-time.Second)) || d.Timestamp.After(time.Now()) {
+		t.Errorf("Datagram.Timestamp = %v, want around %v", d.Timestamp, before)
+	}
+
+	if runtime.GOOS == "linux" && d.IfIndex == 0 {
+		t.Errorf("Datagram.IfIndex = 0, want interface index")
+	}
+}
+
+func TestMultiListener_ReadDatagram(t *testing.T) {
+	groups := []net.IP{
+		IPv4AdminLocalGroup,
+		IPv4GlobalGroup,
+	}
+
+	l, err := NewMultiListener(groups, nil)
+	if err != nil {
+		t.Skipf("cannot listen on multicast groups: %v", err)
+	}
+
+	defer l.Close()
+
+	if l.Memberships() == 0 {
+		t.Skip("no multicast capable interface")
+	}
+
+	for i, group := range groups {
+		conn, err := dialGroup(group, &config{})
+		if err != nil {
+			t.Skipf("cannot send to multicast group: %v", err)
+		}
+
+		raw, err := testPacket(uint16(i)).Encode()
+		if err != nil {
+			t.Fatalf("Packet.Encode() error = %v", err)
+		}
+
+		if _, err := conn.Write(raw); err != nil {
+			conn.Close()
+			t.Skipf("cannot send to multicast group: %v", err)
+		}
+
+		conn.Close()
+
+		received := make(chan *Datagram)
+
+		go func() {
+			d, err := l.ReadDatagram()
+			if err == nil {
+				received <- d
+			}
+		}()
+
+		select {
+		case d := <-received:
+			if string(d.Data) != string(raw) {
+				t.Errorf("Datagram.Data = %v, want %v", d.Data, raw)
+			}
+
+			if runtime.GOOS == "linux" && !d.Group.Equal(group) {
+				t.Errorf("Datagram.Group = %v, want %v", d.Group, group)
+			}
+
+		case <-time.After(time.Second):
+			t.Skip("no multicast loopback")
+		}
+	}
+
+	l.Close()
+
+	if _, err := l.ReadDatagram(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("MultiListener.ReadDatagram() error = %v, want %v", err, net.ErrClosed)
+	}
+}
+
+func TestNewMultiListener_noMembership(t *testing.T) {
+	missing := &net.Interface{Name: "sap-missing0"}
+
+	tests := []struct {
+		name string
+		ifis []*net.Interface
+	}{
+		{"missing interface", []*net.Interface{missing}},
+		{"one interface missing", []*net.Interface{{Name: "lo"}, missing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewMultiListener([]net.IP{IPv4AdminLocalGroup}, tt.ifis)
+			if err == nil {
+				l.Close()
+			}
+
+			if !errors.Is(err, ErrNoMembership) {
+				t.Errorf("NewMultiListener() error = %v, want %v", err, ErrNoMembership)
+			}
+		})
+	}
+}
+
+func TestListener_ReadDatagram_authenticator(t *testing.T) {
+	auth := &HMACAuthenticator{
+		KeyID: "test",
+		Keys:  map[string][]byte{"test": []byte("secret")},
+	}
+
+	l, err := NewListener(IPv4AdminLocalGroup, nil, WithVerification(auth))
+	if err != nil {
+		t.Skipf("cannot listen on multicast group: %v", err)
+	}
+
+	defer l.Close()
+
+	conn, err := dialGroup(IPv4AdminLocalGroup, &config{})
+	if err != nil {
+		t.Skipf("cannot send to multicast group: %v", err)
+	}
+
+	defer conn.Close()
+
+	signed := testPacket(0x0001)
+
+	if err := signed.Sign(auth); err != nil {
+		t.Fatalf("Packet.Sign() error = %v", err)
+	}
+
+	for _, p := range []*Packet{signed, testPacket(0x0002)} {
+		raw, err := p.Encode()
+		if err != nil {
+			t.Fatalf("Packet.Encode() error = %v", err)
+		}
+
+		if _, err := conn.Write(raw); err != nil {
+			t.Skipf("cannot send to multicast group: %v", err)
+		}
+	}
+
+	l.conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	d, err := l.ReadDatagram()
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Skipf("no multicast loopback: %v", err)
+	}
+
+	if err != nil || d.Signer != "test" {
+		t.Errorf("ReadDatagram() = %v, %v, want signer test", d, err)
+	}
+
+	var authErr *AuthenticationError
+
+	d, err = l.ReadDatagram()
+	if !errors.As(err, &authErr) || !errors.Is(err, ErrNotSigned) {
+		t.Fatalf("ReadDatagram() error = %v, want %v", err, ErrNotSigned)
+	}
+
+	if d == nil || d.Source == nil {
+		t.Errorf("ReadDatagram() = %v, want datagram along with error", d)
+	}
+}
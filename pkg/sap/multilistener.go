@@ -0,0 +1,343 @@
+package sap
+
+import (
+	"net"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	interfacePollInterval = 5 * time.Second
+)
+
+var ErrNoMembership = errors.New("no group membership")
+
+type membership struct {
+	ifName string
+	group  string
+}
+
+// MultiListener receives SAP packets on several groups and interfaces through
+// one socket per address family. Each datagram is tagged with the group and
+// interface it arrived on. Interfaces are watched so groups are joined again
+// when an interface goes down and comes back.
+type MultiListener struct {
+	groups        []net.IP
+	ifNames       []string
+	authenticator Authenticator
+	conns         map[string]*net.UDPConn
+	mutex         sync.Mutex
+	joined        map[membership]int
+	datagrams     chan *Datagram
+	errs          chan error
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewMultiListener joins all groups on all given interfaces. If ifis is empty,
+// all multicast capable interfaces are used, including those that appear later.
+// It fails with ErrNoMembership if no group could be joined, or if any group
+// could not be joined on one of the given interfaces.
+func NewMultiListener(groups []net.IP, ifis []*net.Interface, opts ...ListenerOption) (*MultiListener, error) {
+	if len(groups) == 0 {
+		return nil, ErrInvalidGroup
+	}
+
+	c := newListenerConfig(opts)
+
+	l := &MultiListener{
+		groups:        groups,
+		authenticator: c.authenticator,
+		conns:         make(map[string]*net.UDPConn),
+		joined:        make(map[membership]int),
+		datagrams:     make(chan *Datagram),
+		errs:          make(chan error),
+		done:          make(chan struct{}),
+	}
+
+	for _, ifi := range ifis {
+		l.ifNames = append(l.ifNames, ifi.Name)
+	}
+
+	for _, group := range groups {
+		if !group.IsMulticast() {
+			l.closeConns()
+
+			return nil, errors.Wrap(ErrInvalidGroup, group.String())
+		}
+
+		network := udpNetwork(group)
+
+		if _, ok := l.conns[network]; ok {
+			continue
+		}
+
+		// Listening on a multicast address binds to the wildcard address
+		// with a reusable port, without joining the group.
+		conn, err := net.ListenUDP(network, &net.UDPAddr{
+			IP:   group,
+			Port: sapPort,
+		})
+		if err != nil {
+			l.closeConns()
+
+			return nil, err
+		}
+
+		l.conns[network] = conn
+
+		if err := conn.SetReadBuffer(sharedReadBufferSize); err != nil {
+			l.closeConns()
+
+			return nil, err
+		}
+
+		if err := enableControlMessages(conn, network == "udp6"); err != nil {
+			l.closeConns()
+
+			return nil, err
+		}
+	}
+
+	if err := l.checkMemberships(l.join()); err != nil {
+		l.closeConns()
+
+		return nil, err
+	}
+
+	for _, conn := range l.conns {
+		go l.read(conn)
+	}
+
+	go l.watch()
+
+	return l, nil
+}
+
+func (l *MultiListener) interfaces() ([]net.Interface, error) {
+	all, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	ifis := make([]net.Interface, 0)
+
+	for _, ifi := range all {
+		if ifi.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		if len(l.ifNames) > 0 {
+			if !slices.Contains(l.ifNames, ifi.Name) {
+				continue
+			}
+		} else if ifi.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+
+		ifis = append(ifis, ifi)
+	}
+
+	return ifis, nil
+}
+
+func (l *MultiListener) joinGroup(ifi *net.Interface, group net.IP) error {
+	addr := &net.UDPAddr{
+		IP: group,
+	}
+
+	var err error
+
+	if group.To4() != nil {
+		err = ipv4.NewPacketConn(l.conns["udp4"]).JoinGroup(ifi, addr)
+	} else {
+		err = ipv6.NewPacketConn(l.conns["udp6"]).JoinGroup(ifi, addr)
+	}
+
+	// The kernel may have kept the membership while the interface was down
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return nil
+	}
+
+	return err
+}
+
+// join joins all groups on interfaces that are up and not yet joined, and
+// forgets about memberships on interfaces that went away. It returns the last
+// error encountered, if any.
+func (l *MultiListener) join() error {
+	ifis, err := l.interfaces()
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	present := make(map[membership]bool)
+
+	var joinErr error
+
+	for _, ifi := range ifis {
+		for _, group := range l.groups {
+			m := membership{
+				ifName: ifi.Name,
+				group:  group.String(),
+			}
+
+			present[m] = true
+
+			if index, ok := l.joined[m]; ok && index == ifi.Index {
+				continue
+			}
+
+			if err := l.joinGroup(&ifi, group); err != nil {
+				joinErr = errors.Wrapf(err, "joining %s on %s", group, ifi.Name)
+
+				continue
+			}
+
+			l.joined[m] = ifi.Index
+		}
+	}
+
+	for m := range l.joined {
+		if !present[m] {
+			delete(l.joined, m)
+		}
+	}
+
+	return joinErr
+}
+
+// checkMemberships verifies the result of the initial join.
+func (l *MultiListener) checkMemberships(joinErr error) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.joined) == 0 {
+		if joinErr != nil {
+			return errors.Wrapf(ErrNoMembership, "%v", joinErr)
+		}
+
+		return ErrNoMembership
+	}
+
+	for _, name := range l.ifNames {
+		for _, group := range l.groups {
+			if _, ok := l.joined[membership{ifName: name, group: group.String()}]; !ok {
+				return errors.Wrapf(ErrNoMembership, "%s on %s", group, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *MultiListener) watch() {
+	ticker := time.NewTicker(interfacePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case <-ticker.C:
+			// Interfaces that fail are tried again with the next poll
+			l.join()
+		}
+	}
+}
+
+func (l *MultiListener) isGroup(ip net.IP) bool {
+	for _, group := range l.groups {
+		if group.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *MultiListener) read(conn *net.UDPConn) {
+	for {
+		d, err := readDatagram(conn)
+		if err != nil {
+			select {
+			case l.errs <- err:
+			case <-l.done:
+			}
+
+			return
+		}
+
+		// The socket is bound to the wildcard address, so it also sees
+		// packets for groups joined by other sockets on this host.
+		if d.Group != nil && !l.isGroup(d.Group) {
+			continue
+		}
+
+		select {
+		case l.datagrams <- d:
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Memberships returns the number of (group, interface) pairs currently joined.
+func (l *MultiListener) Memberships() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.joined)
+}
+
+// ReadDatagram reads the next packet from any group on any interface. The
+// group and interface index are only known on platforms that report them.
+// Packets that fail verification are handled as with Listener.ReadDatagram.
+func (l *MultiListener) ReadDatagram() (*Datagram, error) {
+	select {
+	case d := <-l.datagrams:
+		if err := d.verify(l.authenticator); err != nil {
+			return d, err
+		}
+
+		return d, nil
+
+	case err := <-l.errs:
+		return nil, err
+
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *MultiListener) ReadPacketRaw() ([]byte, error) {
+	d, err := l.ReadDatagram()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Data, nil
+}
+
+func (l *MultiListener) closeConns() {
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+}
+
+func (l *MultiListener) Close() {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.closeConns()
+	})
+}
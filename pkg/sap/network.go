@@ -1,7 +1,98 @@
 package sap
 
+import (
+	"net"
+	"net/netip"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sapPort = 9875
+	sapTTL  = 255
+
+	// RFC 2974 recommends packets of at most 1024 bytes, but signatures
+	// alone can take up to 1020 bytes of authentication data.
+	maxDatagramSize = 4096
+
+	// Sockets shared by many groups and interfaces need room for bursts of
+	// announcements.
+	sharedReadBufferSize = 1 << 20
+)
+
+// RFC 2974, section 3
+var (
+	IPv4GlobalGroup     = net.IPv4(224, 2, 127, 254)
+	IPv4AdminLocalGroup = net.IPv4(239, 255, 255, 255)
+)
+
+type IPv6Scope uint8
+
+// RFC 4291, section 2.7
 const (
-	sapPort         = 9875
-	sapTTL          = 255
-	maxDatagramSize = 1024
+	IPv6ScopeInterfaceLocal    = IPv6Scope(0x1)
+	IPv6ScopeLinkLocal         = IPv6Scope(0x2)
+	IPv6ScopeAdminLocal        = IPv6Scope(0x4)
+	IPv6ScopeSiteLocal         = IPv6Scope(0x5)
+	IPv6ScopeOrganizationLocal = IPv6Scope(0x8)
+	IPv6ScopeGlobal            = IPv6Scope(0xe)
 )
+
+var ErrInvalidGroup = errors.New("invalid multicast group")
+
+// IPv6Group returns the SAP group for the given scope.
+func IPv6Group(scope IPv6Scope) net.IP {
+	// RFC 2974, section 3:
+	// IPv6 sessions are announced on the address FF0X:0:0:0:0:0:2:7FFE
+	// where X is the 4-bit scope value.
+	ip := net.ParseIP("ff00::2:7ffe")
+	ip[1] = byte(scope & 0xf)
+
+	return ip
+}
+
+// ParseGroup parses a multicast group address. IPv6 addresses may carry a
+// zone ("ff02::2:7ffe%eth0") naming the interface by name or index, which is
+// returned as well. The interface is nil if no zone is given.
+func ParseGroup(s string) (net.IP, *net.Interface, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !addr.IsMulticast() {
+		return nil, nil, errors.Wrap(ErrInvalidGroup, s)
+	}
+
+	ip := net.ParseIP(addr.WithZone("").String())
+
+	zone := addr.Zone()
+	if zone == "" {
+		return ip, nil, nil
+	}
+
+	if index, err := strconv.Atoi(zone); err == nil {
+		ifi, err := net.InterfaceByIndex(index)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return ip, ifi, nil
+	}
+
+	ifi, err := net.InterfaceByName(zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ip, ifi, nil
+}
+
+func udpNetwork(ip net.IP) string {
+	if ip.To4() == nil {
+		return "udp6"
+	}
+
+	return "udp4"
+}
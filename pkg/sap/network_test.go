@@ -0,0 +1,88 @@
+package sap
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIPv6Group(t *testing.T) {
+	tests := []struct {
+		scope IPv6Scope
+		want  string
+	}{
+		{IPv6ScopeLinkLocal, "ff02::2:7ffe"},
+		{IPv6ScopeSiteLocal, "ff05::2:7ffe"},
+		{IPv6ScopeOrganizationLocal, "ff08::2:7ffe"},
+		{IPv6ScopeGlobal, "ff0e::2:7ffe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := IPv6Group(tt.scope); !got.Equal(net.ParseIP(tt.want)) {
+				t.Errorf("IPv6Group() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGroup(t *testing.T) {
+	lo, err := net.InterfaceByIndex(1)
+	if err != nil {
+		t.Skipf("no interface with index 1: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		s       string
+		wantIP  net.IP
+		wantIfi *net.Interface
+		wantErr bool
+	}{
+		{
+			name:   "ipv4",
+			s:      "239.255.255.255",
+			wantIP: IPv4AdminLocalGroup,
+		},
+		{
+			name:   "ipv6",
+			s:      "ff0e::2:7ffe",
+			wantIP: IPv6Group(IPv6ScopeGlobal),
+		},
+		{
+			name:    "ipv6 zone name",
+			s:       "ff02::2:7ffe%" + lo.Name,
+			wantIP:  IPv6Group(IPv6ScopeLinkLocal),
+			wantIfi: lo,
+		},
+		{
+			name:    "ipv6 zone index",
+			s:       "ff02::2:7ffe%1",
+			wantIP:  IPv6Group(IPv6ScopeLinkLocal),
+			wantIfi: lo,
+		},
+		{
+			name:    "unicast",
+			s:       "192.168.1.1",
+			wantErr: true,
+		},
+		{
+			name:    "unknown zone",
+			s:       "ff02::2:7ffe%doesnotexist0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, ifi, err := ParseGroup(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseGroup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !ip.Equal(tt.wantIP) {
+				t.Errorf("ParseGroup() ip = %v, want %v", ip, tt.wantIP)
+			}
+			if (ifi == nil) != (tt.wantIfi == nil) || (ifi != nil && ifi.Index != tt.wantIfi.Index) {
+				t.Errorf("ParseGroup() ifi = %v, want %v", ifi, tt.wantIfi)
+			}
+		})
+	}
+}
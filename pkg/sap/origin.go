@@ -0,0 +1,122 @@
+package sap
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNoOriginAddress = errors.New("no address to use as origin")
+var ErrOriginNotLocal = errors.New("origin is not a local address")
+
+// interfaceOrigin returns the address of ifi to use as origin for
+// announcements sent to the group ip. Addresses of the same family as the
+// group are preferred, and for IPv6 groups beyond link-local scope, addresses
+// that are not link-local.
+func interfaceOrigin(ip net.IP, ifi *net.Interface) (net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	wantV4 := ip.To4() != nil
+	var fallback net.IP
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		if (ipNet.IP.To4() != nil) != wantV4 {
+			continue
+		}
+
+		if !wantV4 && ipNet.IP.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() {
+			if fallback == nil {
+				fallback = ipNet.IP
+			}
+
+			continue
+		}
+
+		return ipNet.IP, nil
+	}
+
+	if fallback != nil {
+		return fallback, nil
+	}
+
+	return nil, errors.Wrap(ErrNoOriginAddress, ifi.Name)
+}
+
+// routeOrigin returns the source address the routing table picks for packets
+// sent to the group ip. Connecting a UDP socket does not send any packets.
+func routeOrigin(ip net.IP) (net.IP, error) {
+	conn, err := net.DialUDP(udpNetwork(ip), nil, &net.UDPAddr{
+		IP:   ip,
+		Port: sapPort,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.IsUnspecified() {
+		return nil, ErrNoOriginAddress
+	}
+
+	return addr.IP, nil
+}
+
+func isLocalAddress(ip net.IP) (bool, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, err
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// resolveOrigin fills in or validates the origin of p as configured.
+func (c *config) resolveOrigin(ip net.IP, p *Packet) error {
+	if c.originFromInterface && (p.Origin == nil || p.Origin.IsUnspecified()) {
+		var origin net.IP
+		var err error
+
+		if c.ifi != nil {
+			origin, err = interfaceOrigin(ip, c.ifi)
+		} else {
+			origin, err = routeOrigin(ip)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		p.Origin = origin
+
+		return nil
+	}
+
+	if c.originValidation {
+		local, err := isLocalAddress(p.Origin)
+		if err != nil {
+			return err
+		}
+
+		if !local {
+			return errors.Wrap(ErrOriginNotLocal, p.Origin.String())
+		}
+	}
+
+	return nil
+}
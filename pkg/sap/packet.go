@@ -8,7 +8,9 @@ import (
 	"io"
 	"net"
 	"strings"
+	"time"
 
+	"github.com/holoplot/go-sap/pkg/sdp"
 	"github.com/pkg/errors"
 )
 
@@ -29,6 +31,9 @@ const (
 	addressV6Flag       = uint8(1 << 4)
 )
 
+// The authentication length counts 32-bit words (RFC 2974, section 3).
+const maxAuthenticationDataSize = 0xff * 4
+
 type MessageType int
 
 const (
@@ -45,6 +50,11 @@ type Packet struct {
 	PayloadType        string
 	AuthenticationData []byte
 	Payload            []byte
+
+	// KeyID and Timeout are only used by encrypted packets. A zero Timeout
+	// leaves the lifetime of the session unbounded.
+	KeyID   uint32
+	Timeout time.Time
 }
 
 func (p *Packet) UniqueID() string {
@@ -56,8 +66,30 @@ func (p *Packet) UniqueID() string {
 var ErrPacketTooShort = errors.New("packet too short")
 var ErrAuthenticationDataTooLong = errors.New("authentication data too long")
 var ErrPacketInvalidIntegrity = errors.New("packet integrity error")
+var ErrPayloadNotSDP = errors.New("payload is not a session description")
+
+// SDP parses the payload of the packet as session description.
+func (p *Packet) SDP() (*sdp.Session, error) {
+	if p.PayloadType != SDPPayloadType {
+		return nil, ErrPayloadNotSDP
+	}
 
+	return sdp.Parse(p.Payload)
+}
+
+// Encode encodes the packet. Encrypted packets need a key, see
+// EncodeWithKeyring.
 func (p *Packet) Encode() ([]byte, error) {
+	return p.encode(nil)
+}
+
+// EncodeWithKeyring encodes the packet, encrypting it with the key for its
+// KeyID from k if the Encrypted flag is set.
+func (p *Packet) EncodeWithKeyring(k Keyring) ([]byte, error) {
+	return p.encode(k)
+}
+
+func (p *Packet) encode(keyring Keyring) ([]byte, error) {
 	writer := new(bytes.Buffer)
 
 	flags := uint8(0)
@@ -88,11 +120,17 @@ func (p *Packet) Encode() ([]byte, error) {
 
 	writer.WriteByte(flags)
 
-	if len(p.AuthenticationData) >= 0x100 {
+	if len(p.AuthenticationData) > maxAuthenticationDataSize {
 		return nil, ErrAuthenticationDataTooLong
 	}
 
-	writer.WriteByte(uint8(len(p.AuthenticationData)))
+	if len(p.AuthenticationData) > 0 {
+		if _, err := DecodeAuthenticationHeader(p.AuthenticationData); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.WriteByte(uint8(len(p.AuthenticationData) / 4))
 
 	binary.Write(writer, binary.BigEndian, p.IDHash)
 	writer.Write(origin)
@@ -125,14 +163,48 @@ func (p *Packet) Encode() ([]byte, error) {
 		closer.Close()
 	}
 
-	writer.Write(payloadBytes.Bytes())
+	payload := payloadBytes.Bytes()
+
+	// RFC 2974, section 7:
+	// The key ID and timeout precede the encrypted payload in the clear.
+	// Compression is applied before encryption.
+	if p.Encrypted {
+		key, ok := lookupKey(keyring, p.KeyID)
+		if !ok {
+			return nil, errors.Wrapf(ErrNoKey, "key ID %08x", p.KeyID)
+		}
+
+		ciphertext, err := encryptPayload(key, payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "encrypting payload")
+		}
+
+		binary.Write(writer, binary.BigEndian, p.KeyID)
+		binary.Write(writer, binary.BigEndian, uint32(sdp.NTP(p.Timeout)))
+
+		payload = ciphertext
+	}
+
+	writer.Write(payload)
 
 	return writer.Bytes(), nil
 }
 
+// DecodePacket decodes an encoded packet. Encrypted packets are returned with
+// their encrypted payload, see DecodePacketWithKeyring.
 func DecodePacket(raw []byte) (*Packet, error) {
-	p := &Packet{}
+	return decodePacket(raw, nil)
+}
+
+// DecodePacketWithKeyring decodes an encoded packet and decrypts its payload
+// with the key for its key ID from k. If k has no such key, the packet is
+// returned with the encrypted payload and an empty payload type.
+func DecodePacketWithKeyring(raw []byte, k Keyring) (*Packet, error) {
+	return decodePacket(raw, k)
+}
 
+func decodePacket(raw []byte, keyring Keyring) (*Packet, error) {
+	p := &Packet{}
 	reader := bytes.NewBuffer(raw)
 
 	var flags uint8
@@ -181,10 +253,43 @@ func DecodePacket(raw []byte) (*Packet, error) {
 	}
 
 	if authLen > 0 {
-		p.AuthenticationData = make([]byte, authLen)
+		p.AuthenticationData = make([]byte, int(authLen)*4)
 		if err := binary.Read(reader, binary.BigEndian, p.AuthenticationData); err != nil {
 			return nil, err
 		}
+
+		if _, err := DecodeAuthenticationHeader(p.AuthenticationData); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Encrypted {
+		var timeout uint32
+
+		if err := binary.Read(reader, binary.BigEndian, &p.KeyID); err != nil {
+			return nil, err
+		}
+
+		if err := binary.Read(reader, binary.BigEndian, &timeout); err != nil {
+			return nil, err
+		}
+
+		p.Timeout = sdp.NTPTime(uint64(timeout))
+
+		key, ok := lookupKey(keyring, p.KeyID)
+		if !ok {
+			p.Payload = make([]byte, reader.Len())
+			copy(p.Payload, reader.Bytes())
+
+			return p, nil
+		}
+
+		plaintext, err := decryptPayload(key, reader.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewBuffer(plaintext)
 	}
 
 	var payload bytes.Buffer
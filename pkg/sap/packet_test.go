@@ -416,3 +416,25 @@ func TestPacket_Rencode(t *testing.T) {
 		})
 	}
 }
+
+func TestPacket_SDP(t *testing.T) {
+	p := &Packet{
+		PayloadType: SDPPayloadType,
+		Payload:     []byte("v=0\r\no=- 1 1 IN IP4 192.168.100.254\r\ns=test\r\nt=0 0\r\n"),
+	}
+
+	s, err := p.SDP()
+	if err != nil {
+		t.Fatalf("Packet.SDP() error = %v", err)
+	}
+
+	if s.Name != "test" {
+		t.Errorf("Packet.SDP().Name = %q, want %q", s.Name, "test")
+	}
+
+	p.PayloadType = "application/octet-stream"
+
+	if _, err := p.SDP(); err != ErrPayloadNotSDP {
+		t.Errorf("Packet.SDP() error = %v, want %v", err, ErrPayloadNotSDP)
+	}
+}
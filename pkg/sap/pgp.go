@@ -0,0 +1,49 @@
+package sap
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/pkg/errors"
+)
+
+// PGPAuthenticator implements the PGP authentication type of RFC 2974,
+// section 7.1, where the signature is a detached OpenPGP signature packet.
+// Entity is the private key used for signing. Signatures are verified against
+// the keys in Keyring.
+type PGPAuthenticator struct {
+	Entity  *openpgp.Entity
+	Keyring openpgp.KeyRing
+}
+
+func (a *PGPAuthenticator) Sign(signed []byte) (*AuthenticationHeader, error) {
+	var signature bytes.Buffer
+
+	if err := openpgp.DetachSign(&signature, a.Entity, bytes.NewReader(signed), nil); err != nil {
+		return nil, errors.Wrap(err, "signing packet")
+	}
+
+	return &AuthenticationHeader{
+		Type: AuthenticationTypePGP,
+		Data: signature.Bytes(),
+	}, nil
+}
+
+// Verify returns the fingerprint of the primary key of the signer.
+func (a *PGPAuthenticator) Verify(signed []byte, h *AuthenticationHeader) (string, error) {
+	if h.Type != AuthenticationTypePGP {
+		return "", errors.Wrapf(ErrInvalidSignature, "authentication type %d", h.Type)
+	}
+
+	if a.Keyring == nil {
+		return "", errors.Wrap(ErrInvalidSignature, "no keyring")
+	}
+
+	signer, err := openpgp.CheckDetachedSignature(a.Keyring, bytes.NewReader(signed), bytes.NewReader(h.Data), nil)
+	if err != nil {
+		return "", errors.Wrapf(ErrInvalidSignature, "%v", err)
+	}
+
+	return hex.EncodeToString(signer.PrimaryKey.Fingerprint[:]), nil
+}
@@ -0,0 +1,52 @@
+package sap
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func TestPGPAuthenticator(t *testing.T) {
+	entity, err := openpgp.NewEntity("Announcer", "", "announcer@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("openpgp.NewEntity() error = %v", err)
+	}
+
+	a := &PGPAuthenticator{
+		Entity:  entity,
+		Keyring: openpgp.EntityList{entity},
+	}
+
+	p := testPacket(0x0001)
+
+	if err := p.Sign(a); err != nil {
+		t.Fatalf("Packet.Sign() error = %v", err)
+	}
+
+	if h, _ := p.AuthenticationHeader(); h.Type != AuthenticationTypePGP {
+		t.Errorf("AuthenticationHeader.Type = %d, want %d", h.Type, AuthenticationTypePGP)
+	}
+
+	raw, err := p.Encode()
+	if err != nil {
+		t.Fatalf("Packet.Encode() error = %v", err)
+	}
+
+	signer, err := VerifyPacket(raw, a)
+	if err != nil {
+		t.Fatalf("VerifyPacket() error = %v", err)
+	}
+
+	if want := hex.EncodeToString(entity.PrimaryKey.Fingerprint[:]); signer != want {
+		t.Errorf("VerifyPacket() = %s, want %s", signer, want)
+	}
+
+	raw[len(raw)-3] ^= 0xff
+
+	if _, err := VerifyPacket(raw, a); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyPacket() error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
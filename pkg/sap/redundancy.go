@@ -0,0 +1,94 @@
+package sap
+
+import (
+	"sort"
+
+	"github.com/holoplot/go-sap/pkg/sdp"
+)
+
+// RedundantSession is a redundant stream whose legs are announced as two
+// separate sessions, typically one on each network of a SMPTE ST 2022-7
+// setup. The primary is the session received on the lower interface index.
+type RedundantSession struct {
+	Primary   Session
+	Secondary Session
+	Stream    sdp.RedundantStream
+}
+
+type redundancyKey struct {
+	username  string
+	sessionID string
+	name      string
+}
+
+type redundancyCandidate struct {
+	session Session
+	leg     sdp.StreamLeg
+}
+
+// RedundantSessions correlates the known sessions into redundant pairs.
+// Two sessions are considered legs of the same stream if their SDP shares the
+// o= username, session ID and session name while the first media description
+// is sent to a different destination. Sessions that resolve redundancy
+// themselves through a=group:DUP are left out, see sdp.Session.RedundantStreams.
+func (d *Directory) RedundantSessions() []RedundantSession {
+	sessions := d.Sessions()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].IfIndex != sessions[j].IfIndex {
+			return sessions[i].IfIndex < sessions[j].IfIndex
+		}
+
+		return sessions[i].Packet.UniqueID() < sessions[j].Packet.UniqueID()
+	})
+
+	candidates := make(map[redundancyKey][]redundancyCandidate)
+	keys := make([]redundancyKey, 0)
+
+	for _, s := range sessions {
+		desc, err := s.Packet.SDP()
+		if err != nil || len(desc.Media) == 0 || len(desc.Groups(sdp.GroupSemanticsDuplication)) > 0 {
+			continue
+		}
+
+		leg, err := desc.StreamLeg(&desc.Media[0])
+		if err != nil || leg.Destination == nil {
+			continue
+		}
+
+		key := redundancyKey{
+			username:  desc.Origin.Username,
+			sessionID: desc.Origin.SessionID,
+			name:      desc.Name,
+		}
+
+		if _, ok := candidates[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		candidates[key] = append(candidates[key], redundancyCandidate{
+			session: s,
+			leg:     leg,
+		})
+	}
+
+	pairs := make([]RedundantSession, 0)
+
+	for _, key := range keys {
+		c := candidates[key]
+		if len(c) != 2 || c[0].leg.Destination.Equal(c[1].leg.Destination) {
+			continue
+		}
+
+		pairs = append(pairs, RedundantSession{
+			Primary:   c[0].session,
+			Secondary: c[1].session,
+			Stream: sdp.RedundantStream{
+				Primary:   c[0].leg,
+				Secondary: c[1].leg,
+			},
+		})
+	}
+
+	return pairs
+}
@@ -0,0 +1,90 @@
+package sap
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func redundantTestPacket(hash uint16, origin, group string) *Packet {
+	payload := fmt.Sprintf("v=0\r\n"+
+		"o=- 1234 1 IN IP4 %s\r\n"+
+		"s=Stage left\r\n"+
+		"t=0 0\r\n"+
+		"m=audio 5004 RTP/AVP 98\r\n"+
+		"c=IN IP4 %s/32\r\n"+
+		"a=rtpmap:98 L24/48000/2\r\n", origin, group)
+
+	return &Packet{
+		Type:        MessageTypeAnnouncement,
+		IDHash:      hash,
+		Origin:      net.ParseIP(origin),
+		PayloadType: SDPPayloadType,
+		Payload:     []byte(payload),
+	}
+}
+
+func TestDirectory_RedundantSessions(t *testing.T) {
+	d, _ := newTestDirectory()
+
+	packets := []struct {
+		packet  *Packet
+		ifIndex int
+	}{
+		{redundantTestPacket(0x0001, "192.168.200.10", "239.200.1.1"), 3},
+		{redundantTestPacket(0x0001, "192.168.100.10", "239.100.1.1"), 2},
+		// An unrelated session without a partner
+		{testPacket(0x0002), 2},
+	}
+
+	for _, tt := range packets {
+		raw, err := tt.packet.Encode()
+		if err != nil {
+			t.Fatalf("Packet.Encode() error = %v", err)
+		}
+
+		if _, err := d.HandleDatagram(&Datagram{Data: raw, IfIndex: tt.ifIndex}); err != nil {
+			t.Fatalf("Directory.HandleDatagram() error = %v", err)
+		}
+	}
+
+	pairs := d.RedundantSessions()
+	if len(pairs) != 1 {
+		t.Fatalf("len(Directory.RedundantSessions()) = %d, want 1", len(pairs))
+	}
+
+	pair := pairs[0]
+
+	if pair.Primary.IfIndex != 2 || pair.Secondary.IfIndex != 3 {
+		t.Errorf("RedundantSession interfaces = %d/%d, want 2/3", pair.Primary.IfIndex, pair.Secondary.IfIndex)
+	}
+
+	if !pair.Stream.Primary.Destination.Equal(net.ParseIP("239.100.1.1")) ||
+		!pair.Stream.Secondary.Destination.Equal(net.ParseIP("239.200.1.1")) {
+		t.Errorf("RedundantSession.Stream = %+v, want 239.100.1.1 and 239.200.1.1", pair.Stream)
+	}
+
+	// Legs that share the o= line are told apart by their SAP origin.
+	d, _ = newTestDirectory()
+
+	for _, origin := range []string{"192.168.100.10", "192.168.200.10"} {
+		p := redundantTestPacket(0x0001, origin, "239."+origin[8:11]+".1.1")
+		p.Payload = []byte(strings.Replace(string(p.Payload), "IN IP4 "+origin, "IN IP4 192.168.1.10", 1))
+
+		d.Handle(p)
+	}
+
+	if d.Len() != 2 || len(d.RedundantSessions()) != 1 {
+		t.Errorf("Directory.Len() = %d, RedundantSessions() = %+v, want 2 sessions and a pair", d.Len(), d.RedundantSessions())
+	}
+
+	// The same stream announced twice on one network is not redundant.
+	d, _ = newTestDirectory()
+	d.Handle(redundantTestPacket(0x0001, "192.168.100.10", "239.100.1.1"))
+	d.Handle(redundantTestPacket(0x0002, "192.168.100.10", "239.100.1.1"))
+
+	if pairs := d.RedundantSessions(); len(pairs) != 0 {
+		t.Errorf("Directory.RedundantSessions() = %+v, want none", pairs)
+	}
+}
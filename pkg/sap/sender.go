@@ -6,6 +6,9 @@ import (
 	"math/rand"
 	"net"
 	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 const (
@@ -14,7 +17,29 @@ const (
 )
 
 type config struct {
-	minInterval time.Duration
+	minInterval      time.Duration
+	adaptiveInterval bool
+	ttl              int
+	ifi              *net.Interface
+	loopback         *bool
+
+	originFromInterface bool
+	originValidation    bool
+
+	payloadIDHash bool
+	directory     *Directory
+
+	validation        bool
+	aes67Validation   bool
+	validationWarning func(p *Packet, err error)
+
+	sessionTiming bool
+	announceAhead time.Duration
+
+	authenticator Authenticator
+	keyring       Keyring
+
+	errorHandler func(group net.IP, err error)
 }
 
 type Option func(o *config)
@@ -25,7 +50,130 @@ func WithMinInterval(interval time.Duration) Option {
 	}
 }
 
-func AnnouncePeriodically(ctx context.Context, ip net.IP, p *Packet, opts ...Option) error {
+// WithAdaptiveInterval makes the Announcer and AnnouncePeriodically listen on
+// each group they announce on and include the announcements of other sessions
+// heard there when computing the interval, as described in RFC 2974, section
+// 3.1.
+func WithAdaptiveInterval() Option {
+	return func(c *config) {
+		c.adaptiveInterval = true
+	}
+}
+
+// WithTTL sets the multicast TTL (IPv4) or hop limit (IPv6) of the sent
+// packets. By default, the value required by the scope of the group is used.
+func WithTTL(ttl int) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithInterface sets the interface announcements are sent on. By default, the
+// routing table decides.
+func WithInterface(ifi *net.Interface) Option {
+	return func(c *config) {
+		c.ifi = ifi
+	}
+}
+
+// WithLoopback enables or disables the delivery of sent announcements to
+// listeners on the local host. By default, the system setting is used.
+func WithLoopback(loopback bool) Option {
+	return func(c *config) {
+		c.loopback = &loopback
+	}
+}
+
+// WithOriginFromInterface fills in the origin of packets that have none with
+// the address of the interface the announcements are sent on.
+func WithOriginFromInterface() Option {
+	return func(c *config) {
+		c.originFromInterface = true
+	}
+}
+
+// WithOriginValidation refuses to announce packets whose origin is not an
+// address of this host.
+func WithOriginValidation() Option {
+	return func(c *config) {
+		c.originValidation = true
+	}
+}
+
+// WithPayloadIDHash derives the message ID hash from the payload instead of
+// using the one set in the packet, see PayloadIDHash. Hashes that are already
+// used by a different session from the same origin are skipped.
+func WithPayloadIDHash() Option {
+	return func(c *config) {
+		c.payloadIDHash = true
+	}
+}
+
+// WithDirectory makes the sessions of d known for the collision checks of
+// WithPayloadIDHash, for example those tracked by a monitor in the same
+// process.
+func WithDirectory(d *Directory) Option {
+	return func(c *config) {
+		c.directory = d
+	}
+}
+
+// WithValidation checks payloads before they are announced, see
+// Packet.Validate. If warn is nil, packets that fail are refused. Otherwise
+// warn is called and they are announced anyway.
+func WithValidation(warn func(p *Packet, err error)) Option {
+	return func(c *config) {
+		c.validation = true
+		c.validationWarning = warn
+	}
+}
+
+// WithAES67Validation adds the checks of sdp.Session.ValidateAES67 to those of
+// WithValidation, which it enables in refusing mode if not given.
+func WithAES67Validation() Option {
+	return func(c *config) {
+		c.validation = true
+		c.aes67Validation = true
+	}
+}
+
+// WithSessionTiming honors the t= lines of SDP payloads. Announcements start
+// the given time ahead of the start time of the session and stop with a
+// deletion packet once its stop time has passed. Unbounded sessions are
+// announced right away and until they are removed.
+func WithSessionTiming(ahead time.Duration) Option {
+	return func(c *config) {
+		c.sessionTiming = true
+		c.announceAhead = ahead
+	}
+}
+
+// WithAuthenticator makes announcers sign all sent packets, deletions
+// included. Listeners verify signatures with WithVerification.
+func WithAuthenticator(a Authenticator) Option {
+	return func(c *config) {
+		c.authenticator = a
+	}
+}
+
+// WithKeyring provides the keys to encrypt packets with the Encrypted flag.
+// Packets are encrypted with the key named by their KeyID.
+func WithKeyring(k Keyring) Option {
+	return func(c *config) {
+		c.keyring = k
+	}
+}
+
+// WithErrorHandler makes the Announcer report packets it fails to send to
+// handler along with their group. Announcing continues with the next interval
+// either way. By default, such errors are dropped.
+func WithErrorHandler(handler func(group net.IP, err error)) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+	}
+}
+
+func newConfig(opts []Option) config {
 	c := config{
 		minInterval: minIntervalDefault,
 	}
@@ -34,38 +182,251 @@ func AnnouncePeriodically(ctx context.Context, ip net.IP, p *Packet, opts ...Opt
 		opt(&c)
 	}
 
-	p.Type = MessageTypeAnnouncement
+	return c
+}
 
-	raw, err := p.Encode()
+// encode encodes p and signs it if configured. The signature covers the
+// encoded packet as sent, so encrypted packets are only encrypted once.
+func (c *config) encode(p *Packet) ([]byte, error) {
+	if c.authenticator == nil {
+		return p.encode(c.keyring)
+	}
+
+	unsigned := *p
+	unsigned.AuthenticationData = nil
+
+	signed, err := unsigned.encode(c.keyring)
 	if err != nil {
-		return fmt.Errorf("encoding announcement package: %w", err)
+		return nil, err
 	}
 
+	h, err := c.authenticator.Sign(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := h.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return withAuthenticationData(signed, auth), nil
+}
+
+// RFC 2974, section 3.1
+func announcementInterval(size int, minInterval time.Duration) time.Duration {
+	interval := time.Duration(8*size/bandwidthLimitBits) * time.Second
+
+	if interval < minInterval {
+		interval = minInterval
+	}
+
+	return interval
+}
+
+// RFC 2974, section 3.1
+func announcementOffset(interval time.Duration) time.Duration {
+	intervalSec := int(interval / time.Second)
+
+	if intervalSec*2/3 == 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Intn(intervalSec*2/3)-intervalSec/3) * time.Second
+}
+
+// sessionWindow returns the time to start announcing p at and the time to
+// delete it at as enabled by WithSessionTiming. Both are zero if they don't
+// apply.
+func (c *config) sessionWindow(p *Packet) (time.Time, time.Time) {
+	if !c.sessionTiming {
+		return time.Time{}, time.Time{}
+	}
+
+	desc, err := p.SDP()
+	if err != nil {
+		return time.Time{}, time.Time{}
+	}
+
+	start := desc.Start()
+	if !start.IsZero() {
+		start = start.Add(-c.announceAhead)
+	}
+
+	return start, desc.End()
+}
+
+// defaultTTL returns the TTL or hop limit required by the scope of the group.
+func defaultTTL(ip net.IP) int {
+	if ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return 1
+	}
+
+	// RFC 2974, section 3:
+	// Scope is determined by the group, so announcements are sent with the
+	// maximum TTL and administrative scope boundaries limit their reach.
+	return sapTTL
+}
+
+// groupConn is an unconnected socket sending to a multicast group. The socket
+// is not connected because the outgoing interface would be fixed at the time
+// of connecting, before the multicast options are applied.
+type groupConn struct {
+	conn *net.UDPConn
+	addr *net.UDPAddr
+}
+
+func (g *groupConn) Write(b []byte) (int, error) {
+	return g.conn.WriteToUDP(b, g.addr)
+}
+
+func (g *groupConn) LocalAddr() net.Addr {
+	return g.conn.LocalAddr()
+}
+
+func (g *groupConn) Close() error {
+	return g.conn.Close()
+}
+
+func dialGroup(ip net.IP, c *config) (*groupConn, error) {
 	udpAddr := &net.UDPAddr{
 		IP:   ip,
 		Port: sapPort,
 	}
 
-	network := "udp4"
-	if ip.To4() == nil {
-		network = "udp6"
+	if c.ifi != nil && ip.To4() == nil && ip.IsLinkLocalMulticast() {
+		udpAddr.Zone = c.ifi.Name
+	}
+
+	conn, err := net.ListenUDP(udpNetwork(ip), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := c.ttl
+	if ttl == 0 {
+		ttl = defaultTTL(ip)
+	}
+
+	if ip.To4() != nil {
+		err = setIPv4MulticastOptions(ipv4.NewPacketConn(conn), ttl, c)
+	} else {
+		err = setIPv6MulticastOptions(ipv6.NewPacketConn(conn), ttl, c)
+	}
+
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return &groupConn{
+		conn: conn,
+		addr: udpAddr,
+	}, nil
+}
+
+func setIPv4MulticastOptions(pc *ipv4.PacketConn, ttl int, c *config) error {
+	if err := pc.SetMulticastTTL(ttl); err != nil {
+		return fmt.Errorf("setting multicast TTL: %w", err)
+	}
+
+	if c.ifi != nil {
+		if err := pc.SetMulticastInterface(c.ifi); err != nil {
+			return fmt.Errorf("setting multicast interface: %w", err)
+		}
+	}
+
+	if c.loopback != nil {
+		if err := pc.SetMulticastLoopback(*c.loopback); err != nil {
+			return fmt.Errorf("setting multicast loopback: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func setIPv6MulticastOptions(pc *ipv6.PacketConn, hopLimit int, c *config) error {
+	if err := pc.SetMulticastHopLimit(hopLimit); err != nil {
+		return fmt.Errorf("setting multicast hop limit: %w", err)
+	}
+
+	if c.ifi != nil {
+		if err := pc.SetMulticastInterface(c.ifi); err != nil {
+			return fmt.Errorf("setting multicast interface: %w", err)
+		}
+	}
+
+	if c.loopback != nil {
+		if err := pc.SetMulticastLoopback(*c.loopback); err != nil {
+			return fmt.Errorf("setting multicast loopback: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func AnnouncePeriodically(ctx context.Context, ip net.IP, p *Packet, opts ...Option) error {
+	c := newConfig(opts)
+
+	if err := c.resolveOrigin(ip, p); err != nil {
+		return fmt.Errorf("resolving origin: %w", err)
+	}
+
+	if err := c.resolveIDHash(p, nil); err != nil {
+		return err
+	}
+
+	if err := c.validate(ip, p); err != nil {
+		return fmt.Errorf("validating payload: %w", err)
+	}
+
+	start, end := c.sessionWindow(p)
+	if !end.IsZero() && time.Now().After(end) {
+		return ErrSessionEnded
 	}
 
-	conn, err := net.DialUDP(network, nil, udpAddr)
+	p.Type = MessageTypeAnnouncement
+
+	raw, err := c.encode(p)
+	if err != nil {
+		return fmt.Errorf("encoding announcement package: %w", err)
+	}
+
+	conn, err := dialGroup(ip, &c)
 	if err != nil {
 		return err
 	}
 
 	defer conn.Close()
 
-	// RFC 2974, section 3.1
-	interval := time.Duration(8*len(raw)/bandwidthLimitBits) * time.Second
+	var traffic *trafficMonitor
 
-	if interval < c.minInterval {
-		interval = c.minInterval
+	if c.adaptiveInterval {
+		traffic, err = newTrafficMonitor(ip, c.ifi)
+		if err != nil {
+			return fmt.Errorf("listening for announcements: %w", err)
+		}
+
+		defer traffic.close()
 	}
 
-	intervalSec := int(interval / time.Second)
+	if wait := time.Until(start); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-time.After(wait):
+		}
+	}
+
+	var ended <-chan time.Time
+
+	if !end.IsZero() {
+		ended = time.After(time.Until(end))
+	}
+
+	id := p.UniqueID()
 
 	for {
 		_, err := conn.Write(raw)
@@ -73,26 +434,44 @@ func AnnouncePeriodically(ctx context.Context, ip net.IP, p *Packet, opts ...Opt
 			return fmt.Errorf("sending announcement package: %w", err)
 		}
 
-		// RFC 2974, section 3.1
-		offset := time.Duration(rand.Intn(intervalSec*2/3)-intervalSec/3) * time.Second
+		size := len(raw)
 
-		select {
-		case <-ctx.Done():
-			p.Type = MessageTypeDeletion
+		if traffic != nil {
+			size += traffic.size(func(other string) bool {
+				return other == id
+			})
+		}
 
-			raw, err := p.Encode()
-			if err != nil {
-				return fmt.Errorf("encoding deletion package: %w", err)
-			}
+		interval := announcementInterval(size, c.minInterval)
+		offset := announcementOffset(interval)
 
-			_, err = conn.Write(raw)
-			if err != nil {
-				return fmt.Errorf("sending deletion package: %w", err)
+		select {
+		case <-ctx.Done():
+			if err := c.sendDeletion(conn, *p); err != nil {
+				return err
 			}
 
 			return ctx.Err()
 
+		case <-ended:
+			return c.sendDeletion(conn, *p)
+
 		case <-time.After(interval + offset):
 		}
 	}
 }
+
+func (c *config) sendDeletion(conn *groupConn, p Packet) error {
+	p.Type = MessageTypeDeletion
+
+	raw, err := c.encode(&p)
+	if err != nil {
+		return fmt.Errorf("encoding deletion package: %w", err)
+	}
+
+	if _, err := conn.Write(raw); err != nil {
+		return fmt.Errorf("sending deletion package: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+func TestDialGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		ip           net.IP
+		opts         []Option
+		wantTTL      int
+		wantLoopback bool
+	}{
+		{
+			name:         "default",
+			ip:           IPv4AdminLocalGroup,
+			wantTTL:      sapTTL,
+			wantLoopback: true,
+		},
+		{
+			name:         "link local",
+			ip:           net.IPv4(224, 0, 0, 255),
+			wantTTL:      1,
+			wantLoopback: true,
+		},
+		{
+			name:         "options",
+			ip:           IPv4GlobalGroup,
+			opts:         []Option{WithTTL(15), WithLoopback(false)},
+			wantTTL:      15,
+			wantLoopback: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig(tt.opts)
+
+			conn, err := dialGroup(tt.ip, &c)
+			if err != nil {
+				t.Fatalf("dialGroup() error = %v", err)
+			}
+
+			defer conn.Close()
+
+			pc := ipv4.NewPacketConn(conn.conn)
+
+			if ttl, err := pc.MulticastTTL(); err != nil || ttl != tt.wantTTL {
+				t.Errorf("MulticastTTL() = %d, %v, want %d", ttl, err, tt.wantTTL)
+			}
+
+			if loopback, err := pc.MulticastLoopback(); err != nil || loopback != tt.wantLoopback {
+				t.Errorf("MulticastLoopback() = %v, %v, want %v", loopback, err, tt.wantLoopback)
+			}
+		})
+	}
+}
+
+func TestConfig_resolveOrigin(t *testing.T) {
+	lo, err := net.InterfaceByIndex(1)
+	if err != nil || lo.Flags&net.FlagLoopback == 0 {
+		t.Skip("no loopback interface with index 1")
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		origin  net.IP
+		want    net.IP
+		wantErr error
+	}{
+		{
+			name:   "from interface",
+			opts:   []Option{WithInterface(lo), WithOriginFromInterface()},
+			origin: nil,
+			want:   net.IPv4(127, 0, 0, 1),
+		},
+		{
+			name:   "explicit origin is kept",
+			opts:   []Option{WithInterface(lo), WithOriginFromInterface()},
+			origin: net.IPv4(192, 0, 2, 1),
+			want:   net.IPv4(192, 0, 2, 1),
+		},
+		{
+			name:   "local origin",
+			opts:   []Option{WithOriginValidation()},
+			origin: net.IPv4(127, 0, 0, 1),
+			want:   net.IPv4(127, 0, 0, 1),
+		},
+		{
+			name:    "foreign origin",
+			opts:    []Option{WithOriginValidation()},
+			origin:  net.IPv4(192, 0, 2, 1),
+			wantErr: ErrOriginNotLocal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig(tt.opts)
+
+			p := testPacket(0x0001)
+			p.Origin = tt.origin
+
+			err := c.resolveOrigin(IPv4AdminLocalGroup, p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("config.resolveOrigin() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr == nil && !p.Origin.Equal(tt.want) {
+				t.Errorf("config.resolveOrigin() origin = %v, want %v", p.Origin, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package sap
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/holoplot/go-sap/pkg/sdp"
+)
+
+func timedTestPacket(hash uint16, start, stop time.Time) *Packet {
+	p := testPacket(hash)
+	p.Payload = []byte(fmt.Sprintf("v=0\r\n"+
+		"o=- %d 1 IN IP4 192.168.100.254\r\n"+
+		"s=test\r\n"+
+		"t=%d %d\r\n", hash, sdp.NTP(start), sdp.NTP(stop)))
+
+	return p
+}
+
+func TestDirectory_Expire_stopTime(t *testing.T) {
+	d, clock := newTestDirectory()
+
+	stop := clock.t.Add(5 * time.Minute)
+	p := timedTestPacket(0x0001, clock.t.Add(-time.Hour), stop)
+
+	d.Handle(p)
+
+	s, ok := d.Session(p.UniqueID())
+	if !ok {
+		t.Fatalf("Directory.Session() did not find session")
+	}
+
+	if !s.End.Equal(stop) || !s.ExpiresAt().Equal(stop) {
+		t.Errorf("Session ends %v and expires %v, want %v", s.End, s.ExpiresAt(), stop)
+	}
+
+	clock.advance(6 * time.Minute)
+
+	if expired := d.Expire(); len(expired) != 1 {
+		t.Errorf("Directory.Expire() = %d sessions, want 1", len(expired))
+	}
+
+	// Late announcements of the session that ended are ignored.
+	d.Handle(p)
+
+	if d.Len() != 0 {
+		t.Errorf("Directory.Len() = %d, want 0", d.Len())
+	}
+}
+
+func TestAnnouncer_sessionTiming(t *testing.T) {
+	conn := listenLoopback(t)
+
+	now := time.Now().Truncate(time.Second)
+	ip := net.IPv4(127, 0, 0, 1)
+
+	a := NewAnnouncer(WithSessionTiming(10 * time.Minute))
+	defer a.close()
+
+	if _, err := a.Add(ip, timedTestPacket(0x0001, now.Add(-2*time.Hour), now.Add(-time.Hour))); !errors.Is(err, ErrSessionEnded) {
+		t.Errorf("Announcer.Add() error = %v, want %v", err, ErrSessionEnded)
+	}
+
+	an, err := a.Add(ip, timedTestPacket(0x0002, now.Add(time.Hour), now.Add(2*time.Hour)))
+	if err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	// Nothing is due until ten minutes before the start.
+	wait, failed := a.sendDue(now)
+	if len(failed) != 0 {
+		t.Fatalf("Announcer.sendDue() failed = %v", failed)
+	}
+
+	if want := 50 * time.Minute; wait != want {
+		t.Errorf("Announcer.sendDue() = %v, want %v", wait, want)
+	}
+
+	if _, failed := a.sendDue(now.Add(wait)); len(failed) != 0 {
+		t.Fatalf("Announcer.sendDue() failed = %v", failed)
+	}
+
+	if p := readTestPacket(t, conn); p.Type != MessageTypeAnnouncement || p.IDHash != an.Packet().IDHash {
+		t.Errorf("got packet %04x type %d, want announcement", p.IDHash, p.Type)
+	}
+
+	if _, failed := a.sendDue(now.Add(2 * time.Hour)); len(failed) != 0 {
+		t.Fatalf("Announcer.sendDue() failed = %v", failed)
+	}
+
+	if p := readTestPacket(t, conn); p.Type != MessageTypeDeletion {
+		t.Errorf("got packet type %d after the stop time, want deletion", p.Type)
+	}
+
+	if n := len(a.Announcements()); n != 0 {
+		t.Errorf("len(Announcer.Announcements()) = %d, want 0", n)
+	}
+}
+
+func TestAnnouncement_Update_sessionTiming(t *testing.T) {
+	conn := listenLoopback(t)
+
+	now := time.Now().Truncate(time.Second)
+	ip := net.IPv4(127, 0, 0, 1)
+
+	a := NewAnnouncer(WithSessionTiming(10 * time.Minute))
+	defer a.close()
+
+	an, err := a.Add(ip, timedTestPacket(0x0001, now.Add(time.Hour), now.Add(2*time.Hour)))
+	if err != nil {
+		t.Fatalf("Announcer.Add() error = %v", err)
+	}
+
+	ended := timedTestPacket(0x0001, now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	if err := an.Update(ended.Payload); !errors.Is(err, ErrSessionEnded) {
+		t.Errorf("Announcement.Update() error = %v, want %v", err, ErrSessionEnded)
+	}
+
+	// Moving the start later postpones the first announcement.
+	later := timedTestPacket(0x0001, now.Add(3*time.Hour), now.Add(4*time.Hour))
+
+	if err := an.Update(later.Payload); err != nil {
+		t.Fatalf("Announcement.Update() error = %v", err)
+	}
+
+	if want := now.Add(3*time.Hour - 10*time.Minute); !an.next.Equal(want) {
+		t.Errorf("Announcement scheduled for %v, want %v", an.next, want)
+	}
+
+	// A session that has started is announced right away.
+	running := timedTestPacket(0x0001, now.Add(-time.Hour), now.Add(time.Hour))
+
+	if err := an.Update(running.Payload); err != nil {
+		t.Fatalf("Announcement.Update() error = %v", err)
+	}
+
+	if p := readTestPacket(t, conn); p.Type != MessageTypeAnnouncement || p.IDHash != an.Packet().IDHash {
+		t.Errorf("got packet %04x type %d, want announcement of %04x", p.IDHash, p.Type, an.Packet().IDHash)
+	}
+
+	if !an.next.After(now) || !an.end.Equal(now.Add(time.Hour)) {
+		t.Errorf("Announcement next %v, end %v, want the next interval and the new stop time", an.next, an.end)
+	}
+}
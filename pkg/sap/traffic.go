@@ -0,0 +1,91 @@
+package sap
+
+import (
+	"net"
+	"sync"
+)
+
+// trafficMonitor listens on a group and keeps track of the size of all
+// announcements heard there, so the announcement interval can be computed
+// from the total traffic as described in RFC 2974, section 3.1.
+type trafficMonitor struct {
+	listener  *Listener
+	directory *Directory
+	mutex     sync.Mutex
+	sizes     map[string]int
+}
+
+func newTrafficMonitor(ip net.IP, ifi *net.Interface) (*trafficMonitor, error) {
+	l, err := NewListener(ip, ifi)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &trafficMonitor{
+		listener:  l,
+		directory: NewDirectory(),
+		sizes:     make(map[string]int),
+	}
+
+	go m.run()
+
+	return m, nil
+}
+
+func (m *trafficMonitor) run() {
+	for {
+		raw, err := m.listener.ReadPacketRaw()
+		if err != nil {
+			return
+		}
+
+		m.handle(raw)
+	}
+}
+
+func (m *trafficMonitor) handle(raw []byte) {
+	p, err := DecodePacket(raw)
+	if err != nil {
+		return
+	}
+
+	m.mutex.Lock()
+	m.sizes[p.UniqueID()] = len(raw)
+	m.mutex.Unlock()
+
+	m.directory.Handle(p)
+}
+
+// size returns the total size of all live announcements on the group for which
+// exclude returns false.
+func (m *trafficMonitor) size(exclude func(id string) bool) int {
+	m.directory.Expire()
+
+	live := make(map[string]bool)
+
+	for _, s := range m.directory.Sessions() {
+		live[s.Packet.UniqueID()] = true
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	size := 0
+
+	for id, n := range m.sizes {
+		if !live[id] {
+			delete(m.sizes, id)
+			continue
+		}
+
+		if !exclude(id) {
+			size += n
+		}
+	}
+
+	return size
+}
+
+func (m *trafficMonitor) close() {
+	m.listener.Close()
+}
@@ -0,0 +1,99 @@
+package sap
+
+import (
+	"net"
+
+	"github.com/holoplot/go-sap/pkg/sdp"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// multicastScope returns the scope of a multicast address, using the IPv6
+// scope values for both families. All of 239.0.0.0/8 is treated as one scope,
+// as 239.255.255.255 is the SAP group of the whole range (RFC 2974, section 3).
+func multicastScope(ip net.IP) IPv6Scope {
+	if ip4 := ip.To4(); ip4 != nil {
+		switch {
+		case ip4[0] == 224 && ip4[1] == 0 && ip4[2] == 0:
+			return IPv6ScopeLinkLocal
+		case ip4[0] == 239:
+			return IPv6ScopeSiteLocal
+		}
+
+		return IPv6ScopeGlobal
+	}
+
+	return IPv6Scope(ip[1] & 0xf)
+}
+
+// Validate checks an SDP payload before it is announced on group. Besides the
+// checks of sdp.Session.Validate, the o= address must match the origin of the
+// packet, and all connection addresses must be multicast groups of the same
+// scope as the SAP group, as RFC 2974, section 3 requires.
+func (p *Packet) Validate(group net.IP) error {
+	desc, err := p.SDP()
+	if err != nil {
+		return err
+	}
+
+	if err := desc.Validate(); err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(desc.Origin.Address); ip != nil && p.Origin != nil && !ip.Equal(p.Origin) {
+		return errors.Wrapf(ErrInvalidPayload, "o= address %s does not match origin %s", ip, p.Origin)
+	}
+
+	connections := make([]sdp.Connection, 0)
+
+	if desc.Connection != nil {
+		connections = append(connections, *desc.Connection)
+	}
+
+	for _, m := range desc.Media {
+		connections = append(connections, m.Connections...)
+	}
+
+	for _, c := range connections {
+		ip := c.IP()
+		if ip == nil {
+			continue
+		}
+
+		if !ip.IsMulticast() {
+			return errors.Wrapf(ErrInvalidPayload, "c= address %s is not multicast", ip)
+		}
+
+		sameFamily := (ip.To4() == nil) == (group.To4() == nil)
+
+		if sameFamily && multicastScope(ip) != multicastScope(group) {
+			return errors.Wrapf(ErrInvalidPayload, "c= address %s is not in the scope of group %s", ip, group)
+		}
+	}
+
+	return nil
+}
+
+// validate runs the checks enabled by WithValidation and WithAES67Validation.
+// In warning mode, problems are reported and nil is returned.
+func (c *config) validate(group net.IP, p *Packet) error {
+	if !c.validation {
+		return nil
+	}
+
+	err := p.Validate(group)
+
+	if err == nil && c.aes67Validation {
+		desc, _ := p.SDP()
+		err = desc.ValidateAES67()
+	}
+
+	if err != nil && c.validationWarning != nil {
+		c.validationWarning(p, err)
+
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,98 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/holoplot/go-sap/pkg/sdp"
+)
+
+const validateTestSDP = "v=0\r\n" +
+	"o=- 1 1 IN IP4 192.168.100.254\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 5004 RTP/AVP 98\r\n" +
+	"c=IN IP4 239.69.1.1/32\r\n" +
+	"a=rtpmap:98 L24/48000/2\r\n"
+
+func TestPacket_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		group   net.IP
+		old     string
+		new     string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			group: IPv4AdminLocalGroup,
+		},
+		{
+			name:    "origin",
+			group:   IPv4AdminLocalGroup,
+			old:     "IP4 192.168.100.254",
+			new:     "IP4 192.168.100.1",
+			wantErr: ErrInvalidPayload,
+		},
+		{
+			name:    "unicast",
+			group:   IPv4AdminLocalGroup,
+			old:     "239.69.1.1/32",
+			new:     "192.168.100.1",
+			wantErr: ErrInvalidPayload,
+		},
+		{
+			name:    "scope",
+			group:   IPv4GlobalGroup,
+			wantErr: ErrInvalidPayload,
+		},
+		{
+			name:    "broken",
+			group:   IPv4AdminLocalGroup,
+			old:     "s=test\r\n",
+			new:     "",
+			wantErr: sdp.ErrMissingField,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPacket(0x0001)
+			p.Payload = []byte(strings.Replace(validateTestSDP, tt.old, tt.new, 1))
+
+			if err := p.Validate(tt.group); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Packet.Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnnouncer_validation(t *testing.T) {
+	p := testPacket(0x0001)
+	p.Payload = []byte(strings.Replace(validateTestSDP, "239.69.1.1/32", "192.168.100.1", 1))
+
+	ip := net.IPv4(239, 255, 255, 255)
+
+	a := NewAnnouncer(WithValidation(nil), WithLoopback(false))
+	defer a.close()
+
+	if _, err := a.Add(ip, p); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("Announcer.Add() error = %v, want %v", err, ErrInvalidPayload)
+	}
+
+	var warnings []error
+
+	a = NewAnnouncer(WithValidation(func(_ *Packet, err error) {
+		warnings = append(warnings, err)
+	}), WithLoopback(false))
+	defer a.close()
+
+	if _, err := a.Add(ip, p); err != nil {
+		t.Errorf("Announcer.Add() error = %v in warning mode", err)
+	}
+
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrInvalidPayload) {
+		t.Errorf("warnings = %v, want one %v", warnings, ErrInvalidPayload)
+	}
+}
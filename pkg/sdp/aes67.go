@@ -0,0 +1,237 @@
+package sdp
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNotAudio = errors.New("not an RTP audio stream")
+
+// RefClock is the content of an a=ts-refclk attribute (RFC 7273). For PTP
+// clocks, GrandmasterID is the EUI-64 of the grandmaster as written in the
+// attribute, e.g. "C8-0D-32-FF-FE-4C-85-42". It is empty if the
+// attribute only names the PTP version or uses another clock source.
+type RefClock struct {
+	Source        string
+	PTPVersion    string
+	GrandmasterID string
+	Domain        int
+}
+
+// AudioStream is the typed view of an AES67 / SMPTE ST 2110-30 media
+// description. Session-level attributes are taken into account where the
+// media description does not override them.
+type AudioStream struct {
+	ID          string
+	Destination net.IP
+	Port        int
+	TTL         int
+	PayloadType int
+	Encoding    string
+	SampleRate  int
+	Channels    int
+	PacketTime  time.Duration
+	FrameCount  int
+	RefClock    RefClock
+
+	// SourceFilters are the RFC 4570 filters in effect for the stream,
+	// used for source-specific multicast joins.
+	SourceFilters []SourceFilter
+
+	// MediaClockOffset is the RTP timestamp of the PTP epoch as given by
+	// a=mediaclk:direct=<offset> (RFC 7273).
+	MediaClockOffset uint32
+}
+
+func (s *Session) attribute(m *Media, name string) (string, bool) {
+	if v, ok := m.Attributes.Get(name); ok {
+		return v, true
+	}
+
+	return s.Attributes.Get(name)
+}
+
+func (s *Session) connection(m *Media) *Connection {
+	if len(m.Connections) > 0 {
+		return &m.Connections[0]
+	}
+
+	return s.Connection
+}
+
+// AudioStreams returns the typed view of all audio media descriptions of the
+// session.
+func (s *Session) AudioStreams() ([]AudioStream, error) {
+	streams := make([]AudioStream, 0)
+
+	for i := range s.Media {
+		if s.Media[i].Type != "audio" {
+			continue
+		}
+
+		as, err := s.AudioStream(&s.Media[i])
+		if err != nil {
+			return nil, err
+		}
+
+		streams = append(streams, *as)
+	}
+
+	return streams, nil
+}
+
+// AudioStream returns the typed view of the given media description, which
+// must belong to the session.
+func (s *Session) AudioStream(m *Media) (*AudioStream, error) {
+	if m.Type != "audio" || !strings.HasPrefix(m.Protocol, "RTP/") || len(m.Formats) == 0 {
+		return nil, ErrNotAudio
+	}
+
+	as := &AudioStream{
+		Port:     m.Port,
+		Channels: 1,
+	}
+
+	as.ID, _ = m.Attributes.Get("mid")
+
+	if c := s.connection(m); c != nil {
+		as.Destination = c.IP()
+		as.TTL = c.TTL
+	}
+
+	pt, err := strconv.Atoi(m.Formats[0])
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidValue, "payload type %q", m.Formats[0])
+	}
+
+	as.PayloadType = pt
+
+	if err := as.parseRTPMap(m.Attributes.GetAll("rtpmap")); err != nil {
+		return nil, err
+	}
+
+	if as.SourceFilters, err = s.SourceFilters(m); err != nil {
+		return nil, err
+	}
+
+	if v, ok := s.attribute(m, "ptime"); ok {
+		ms, err := strconv.ParseFloat(v, 64)
+		if err != nil || ms < 0 {
+			return nil, errors.Wrapf(ErrInvalidValue, "ptime %q", v)
+		}
+
+		as.PacketTime = time.Duration(math.Round(ms * float64(time.Millisecond)))
+	}
+
+	if v, ok := s.attribute(m, "framecount"); ok {
+		if as.FrameCount, err = strconv.Atoi(v); err != nil {
+			return nil, errors.Wrapf(ErrInvalidValue, "framecount %q", v)
+		}
+	}
+
+	if v, ok := s.attribute(m, "ts-refclk"); ok {
+		if as.RefClock, err = parseRefClock(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := s.attribute(m, "mediaclk"); ok {
+		if as.MediaClockOffset, err = parseMediaClock(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return as, nil
+}
+
+// RFC 4566, section 6:
+// a=rtpmap:<payload type> <encoding name>/<clock rate>[/<encoding parameters>]
+// For audio, the encoding parameters are the number of channels.
+func (as *AudioStream) parseRTPMap(values []string) error {
+	for _, v := range values {
+		pt, format, _ := strings.Cut(v, " ")
+		if pt != strconv.Itoa(as.PayloadType) {
+			continue
+		}
+
+		parts := strings.Split(format, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return errors.Wrapf(ErrInvalidValue, "rtpmap %q", v)
+		}
+
+		as.Encoding = parts[0]
+
+		rate, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return errors.Wrapf(ErrInvalidValue, "rtpmap %q", v)
+		}
+
+		as.SampleRate = rate
+
+		if len(parts) == 3 {
+			if as.Channels, err = strconv.Atoi(parts[2]); err != nil {
+				return errors.Wrapf(ErrInvalidValue, "rtpmap %q", v)
+			}
+		}
+
+		return nil
+	}
+
+	return errors.Wrapf(ErrMissingField, "rtpmap for payload type %d", as.PayloadType)
+}
+
+// RFC 7273, section 4.8:
+// a=ts-refclk:ptp=<ptp version>[:<gmid>[:<domain>]]
+// The gmid may also be "traceable".
+func parseRefClock(v string) (RefClock, error) {
+	source, params, _ := strings.Cut(v, "=")
+	rc := RefClock{Source: source}
+
+	if source != "ptp" {
+		return rc, nil
+	}
+
+	parts := strings.Split(params, ":")
+	rc.PTPVersion = parts[0]
+
+	if len(parts) > 1 && parts[1] != "traceable" {
+		rc.GrandmasterID = parts[1]
+	}
+
+	if len(parts) > 2 {
+		domain, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return rc, errors.Wrapf(ErrInvalidValue, "ts-refclk %q", v)
+		}
+
+		rc.Domain = domain
+	}
+
+	return rc, nil
+}
+
+// RFC 7273, section 5.2:
+// a=mediaclk:direct=<offset>[ rate=<rate>]
+func parseMediaClock(v string) (uint32, error) {
+	fields := strings.Fields(v)
+	if len(fields) == 0 {
+		return 0, errors.Wrapf(ErrInvalidValue, "mediaclk %q", v)
+	}
+
+	offset, ok := strings.CutPrefix(fields[0], "direct=")
+	if !ok {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(offset, 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidValue, "mediaclk %q", v)
+	}
+
+	return uint32(n), nil
+}
@@ -0,0 +1,93 @@
+package sdp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSession_AudioStreams(t *testing.T) {
+	s, err := Parse([]byte(testSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	streams, err := s.AudioStreams()
+	if err != nil {
+		t.Fatalf("Session.AudioStreams() error = %v", err)
+	}
+
+	if len(streams) != 2 {
+		t.Fatalf("len(Session.AudioStreams()) = %d, want 2", len(streams))
+	}
+
+	as := streams[1]
+
+	if as.ID != "ra1" || !as.Destination.Equal(net.ParseIP("239.200.254.1")) || as.Port != 5004 || as.TTL != 5 {
+		t.Errorf("AudioStream = %+v, want ra1 239.200.254.1:5004 TTL 5", as)
+	}
+
+	if as.PayloadType != 98 || as.Encoding != "L24" || as.SampleRate != 48000 || as.Channels != 8 {
+		t.Errorf("AudioStream = %+v, want 98 L24/48000/8", as)
+	}
+
+	if as.PacketTime != 125*time.Microsecond || as.FrameCount != 6 {
+		t.Errorf("AudioStream = %+v, want ptime 125µs and 6 frames", as)
+	}
+
+	wantRefClock := RefClock{
+		Source:        "ptp",
+		PTPVersion:    "IEEE1588-2008",
+		GrandmasterID: "C8-0D-32-FF-FE-4C-85-42",
+		Domain:        0,
+	}
+
+	if as.RefClock != wantRefClock {
+		t.Errorf("AudioStream.RefClock = %+v, want %+v", as.RefClock, wantRefClock)
+	}
+}
+
+func TestSession_AudioStream(t *testing.T) {
+	s, err := Parse([]byte("v=0\r\n" +
+		"o=- 1 1 IN IP4 192.0.2.1\r\n" +
+		"s=x\r\n" +
+		"c=IN IP4 239.69.1.1/32\r\n" +
+		"t=0 0\r\n" +
+		"a=ts-refclk:ptp=IEEE1588-2008:00-1D-C1-FF-FE-12-34-56:0\r\n" +
+		"a=mediaclk:direct=0\r\n" +
+		"m=audio 5004 RTP/AVP 97\r\n" +
+		"a=rtpmap:97 L16/44100\r\n" +
+		"a=ptime:1\r\n" +
+		"a=ts-refclk:ptp=IEEE1588-2008:39-A7-94-FF-FE-07-CB-D0:127\r\n" +
+		"a=mediaclk:direct=2216659908 rate=48000/1\r\n" +
+		"m=video 5006 RTP/AVP 96\r\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	as, err := s.AudioStream(&s.Media[0])
+	if err != nil {
+		t.Fatalf("Session.AudioStream() error = %v", err)
+	}
+
+	if !as.Destination.Equal(net.ParseIP("239.69.1.1")) || as.TTL != 32 {
+		t.Errorf("AudioStream = %+v, want session connection 239.69.1.1/32", as)
+	}
+
+	if as.Encoding != "L16" || as.SampleRate != 44100 || as.Channels != 1 || as.PacketTime != time.Millisecond {
+		t.Errorf("AudioStream = %+v, want L16/44100 mono with 1ms packets", as)
+	}
+
+	if as.RefClock.GrandmasterID != "39-A7-94-FF-FE-07-CB-D0" || as.RefClock.Domain != 127 {
+		t.Errorf("AudioStream.RefClock = %+v, want media-level clock in domain 127", as.RefClock)
+	}
+
+	if as.MediaClockOffset != 2216659908 {
+		t.Errorf("AudioStream.MediaClockOffset = %d, want 2216659908", as.MediaClockOffset)
+	}
+
+	if _, err := s.AudioStream(&s.Media[1]); !errors.Is(err, ErrNotAudio) {
+		t.Errorf("Session.AudioStream() error = %v, want %v", err, ErrNotAudio)
+	}
+}
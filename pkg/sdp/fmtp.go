@@ -0,0 +1,122 @@
+package sdp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FormatParameter is one entry of an a=fmtp attribute. Value is empty for
+// flags such as "interlace".
+type FormatParameter struct {
+	Name  string
+	Value string
+}
+
+func (p *FormatParameter) String() string {
+	if p.Value == "" {
+		return p.Name
+	}
+
+	return p.Name + "=" + p.Value
+}
+
+// FormatParameters are the parameters of an a=fmtp attribute in the order
+// they were given.
+type FormatParameters []FormatParameter
+
+// Get returns the value of the first parameter with the given name.
+func (f FormatParameters) Get(name string) (string, bool) {
+	for _, p := range f {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// GetAll returns the values of all parameters with the given name.
+func (f FormatParameters) GetAll(name string) []string {
+	values := make([]string, 0)
+
+	for _, p := range f {
+		if p.Name == name {
+			values = append(values, p.Value)
+		}
+	}
+
+	return values
+}
+
+func (f FormatParameters) Has(name string) bool {
+	_, ok := f.Get(name)
+
+	return ok
+}
+
+func (f FormatParameters) String() string {
+	s := make([]string, 0, len(f))
+
+	for _, p := range f {
+		s = append(s, p.String())
+	}
+
+	return strings.Join(s, "; ")
+}
+
+// Attribute returns the parameters as a=fmtp attribute for the given payload
+// type.
+func (f FormatParameters) Attribute(payloadType int) Attribute {
+	return Attribute{
+		Name:  "fmtp",
+		Value: strconv.Itoa(payloadType) + " " + f.String(),
+	}
+}
+
+// ParseFormatParameters parses the value of an a=fmtp attribute in the
+// "name=value; flag" syntax used by RFC 4175 and the SMPTE ST 2110 family.
+func ParseFormatParameters(value string) (int, FormatParameters, error) {
+	pt, rest, _ := strings.Cut(value, " ")
+
+	payloadType, err := strconv.Atoi(pt)
+	if err != nil {
+		return 0, nil, errors.Wrapf(ErrInvalidValue, "fmtp payload type %q", pt)
+	}
+
+	params := make(FormatParameters, 0)
+
+	for _, field := range strings.Split(rest, ";") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		name, v, _ := strings.Cut(field, "=")
+
+		params = append(params, FormatParameter{
+			Name:  strings.TrimSpace(name),
+			Value: strings.TrimSpace(v),
+		})
+	}
+
+	return payloadType, params, nil
+}
+
+// FormatParameters returns the parameters of the a=fmtp attribute for the
+// given payload type of the media description.
+func (m *Media) FormatParameters(payloadType int) (FormatParameters, error) {
+	for _, v := range m.Attributes.GetAll("fmtp") {
+		pt, params, err := ParseFormatParameters(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if pt == payloadType {
+			return params, nil
+		}
+	}
+
+	return nil, errors.Wrapf(ErrMissingField, "fmtp for payload type %d", payloadType)
+}
@@ -0,0 +1,350 @@
+package sdp
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewOrigin returns an origin for the given address with network and address
+// type filled in.
+func NewOrigin(username, sessionID string, version uint64, ip net.IP) Origin {
+	return Origin{
+		Username:       username,
+		SessionID:      sessionID,
+		SessionVersion: version,
+		NetworkType:    NetworkTypeInternet,
+		AddressType:    addressType(ip),
+		Address:        ip.String(),
+	}
+}
+
+// NewConnection returns connection data for the given address with network
+// and address type filled in. The TTL is ignored for IPv6 addresses.
+func NewConnection(ip net.IP, ttl int) Connection {
+	c := Connection{
+		NetworkType: NetworkTypeInternet,
+		AddressType: addressType(ip),
+		Address:     ip.String(),
+	}
+
+	if c.AddressType == AddressTypeIPv4 {
+		c.TTL = ttl
+	}
+
+	return c
+}
+
+func addressType(ip net.IP) string {
+	if ip.To4() != nil {
+		return AddressTypeIPv4
+	}
+
+	return AddressTypeIPv6
+}
+
+type marshaller struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (m *marshaller) fail(err error) {
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+func (m *marshaller) line(typ byte, value string) {
+	if strings.ContainsAny(value, "\r\n") {
+		m.fail(errors.Wrapf(ErrInvalidValue, "%c= contains a line break", typ))
+	}
+
+	m.buf.WriteByte(typ)
+	m.buf.WriteByte('=')
+	m.buf.WriteString(value)
+	m.buf.WriteString("\r\n")
+}
+
+// tokens checks fields that share a line separated by spaces, which can
+// neither be empty nor contain spaces themselves.
+func (m *marshaller) tokens(typ byte, fields ...string) {
+	for _, f := range fields {
+		if f == "" || strings.ContainsAny(f, " \t") {
+			m.fail(errors.Wrapf(ErrInvalidValue, "%c= field %q", typ, f))
+
+			return
+		}
+	}
+}
+
+func (m *marshaller) origin(o *Origin) {
+	m.tokens('o', o.Username, o.SessionID, o.NetworkType, o.AddressType, o.Address)
+	m.line('o', o.String())
+}
+
+func (m *marshaller) connection(c *Connection) {
+	m.tokens('c', c.NetworkType, c.AddressType, c.Address)
+
+	if strings.Contains(c.Address, "/") || c.TTL < 0 || c.NumAddresses < 0 {
+		m.fail(errors.Wrapf(ErrInvalidValue, "c= address %q", c.Address))
+	}
+
+	// RFC 8866, section 5.7: IPv6 addresses carry no TTL.
+	if c.AddressType == AddressTypeIPv6 && c.TTL != 0 {
+		m.fail(errors.Wrapf(ErrInvalidValue, "c= TTL %d for IPv6 address", c.TTL))
+	}
+
+	m.line('c', c.String())
+}
+
+// typedTime formats d for the line typ. Typed times count whole seconds.
+func (m *marshaller) typedTime(typ byte, d time.Duration) string {
+	if d%time.Second != 0 {
+		m.fail(errors.Wrapf(ErrInvalidValue, "%c= time %v is not whole seconds", typ, d))
+	}
+
+	return formatTypedTime(d)
+}
+
+func (m *marshaller) repeat(r *Repeat) {
+	if len(r.Offsets) == 0 {
+		m.fail(errors.Wrap(ErrInvalidValue, "r= has no offsets"))
+	}
+
+	m.typedTime('r', r.Interval)
+	m.typedTime('r', r.Duration)
+
+	for _, offset := range r.Offsets {
+		m.typedTime('r', offset)
+	}
+
+	m.line('r', r.String())
+}
+
+func (m *marshaller) bandwidth(b *Bandwidth) {
+	if b.Type == "" || strings.Contains(b.Type, ":") {
+		m.fail(errors.Wrapf(ErrInvalidValue, "b= type %q", b.Type))
+	}
+
+	m.line('b', b.String())
+}
+
+func (m *marshaller) attribute(a *Attribute) {
+	if strings.Contains(a.Name, ":") {
+		m.fail(errors.Wrapf(ErrInvalidValue, "a= name %q", a.Name))
+	}
+
+	m.line('a', a.String())
+}
+
+func (m *marshaller) media(media *Media) {
+	m.tokens('m', append([]string{media.Type, media.Protocol}, media.Formats...)...)
+
+	if len(media.Formats) == 0 {
+		m.fail(errors.Wrapf(ErrInvalidValue, "m=%s has no formats", media.Type))
+	}
+
+	if media.Port < 0 || media.Port > 0xffff || media.NumPorts < 0 {
+		m.fail(errors.Wrapf(ErrInvalidValue, "m=%s port %d", media.Type, media.Port))
+	}
+
+	m.line('m', media.String())
+}
+
+func (o *Origin) String() string {
+	return strings.Join([]string{
+		o.Username,
+		o.SessionID,
+		strconv.FormatUint(o.SessionVersion, 10),
+		o.NetworkType,
+		o.AddressType,
+		o.Address,
+	}, " ")
+}
+
+func (c *Connection) String() string {
+	address := c.Address
+
+	// RFC 8866, section 5.7
+	if c.AddressType == AddressTypeIPv6 {
+		if c.NumAddresses > 0 {
+			address += "/" + strconv.Itoa(c.NumAddresses)
+		}
+	} else if c.TTL > 0 || c.NumAddresses > 0 {
+		address += "/" + strconv.Itoa(c.TTL)
+
+		if c.NumAddresses > 0 {
+			address += "/" + strconv.Itoa(c.NumAddresses)
+		}
+	}
+
+	return strings.Join([]string{c.NetworkType, c.AddressType, address}, " ")
+}
+
+func (b *Bandwidth) String() string {
+	return b.Type + ":" + strconv.FormatUint(b.Value, 10)
+}
+
+func (a *Attribute) String() string {
+	if a.Value == "" {
+		return a.Name
+	}
+
+	return a.Name + ":" + a.Value
+}
+
+// formatTypedTime uses the largest unit of RFC 8866, section 5.10 that
+// represents d exactly.
+func formatTypedTime(d time.Duration) string {
+	if d == 0 {
+		return "0"
+	}
+
+	for _, unit := range []byte{'d', 'h', 'm'} {
+		if d%typedTimeUnits[unit] == 0 {
+			return strconv.FormatInt(int64(d/typedTimeUnits[unit]), 10) + string(unit)
+		}
+	}
+
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+func (r *Repeat) String() string {
+	fields := []string{
+		formatTypedTime(r.Interval),
+		formatTypedTime(r.Duration),
+	}
+
+	for _, offset := range r.Offsets {
+		fields = append(fields, formatTypedTime(offset))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func (m *Media) String() string {
+	port := strconv.Itoa(m.Port)
+
+	if m.NumPorts > 0 {
+		port += "/" + strconv.Itoa(m.NumPorts)
+	}
+
+	return strings.Join(append([]string{m.Type, port, m.Protocol}, m.Formats...), " ")
+}
+
+// Marshal serializes the session description with CRLF line endings and the
+// fields in the order given by RFC 8866, section 5. Values the parser could
+// not read back, such as fields with spaces on the o=, c= and m= lines, media
+// descriptions without formats, repeat times without offsets, TTLs of IPv6
+// connections or times that are not whole seconds, are refused with
+// ErrInvalidValue. Parsing the
+// output yields the same Session, with two exceptions: an empty session name
+// is written as a single space and missing timings as "t=0 0", as both fields
+// are mandatory.
+func (s *Session) Marshal() ([]byte, error) {
+	if s.Version != 0 {
+		return nil, ErrUnsupportedVersion
+	}
+
+	m := &marshaller{}
+
+	m.line('v', strconv.Itoa(s.Version))
+	m.origin(&s.Origin)
+
+	name := s.Name
+	if name == "" {
+		name = " "
+	}
+
+	m.line('s', name)
+
+	if s.Information != "" {
+		m.line('i', s.Information)
+	}
+
+	if s.URI != "" {
+		m.line('u', s.URI)
+	}
+
+	for _, e := range s.Emails {
+		m.line('e', e)
+	}
+
+	for _, p := range s.Phones {
+		m.line('p', p)
+	}
+
+	if s.Connection != nil {
+		m.connection(s.Connection)
+	}
+
+	for _, b := range s.Bandwidths {
+		m.bandwidth(&b)
+	}
+
+	timings := s.Timings
+	if len(timings) == 0 {
+		timings = []Timing{{}}
+	}
+
+	for _, t := range timings {
+		m.line('t', fmt.Sprintf("%d %d", t.Start, t.Stop))
+
+		for _, r := range t.Repeats {
+			m.repeat(&r)
+		}
+	}
+
+	if len(s.TimeZones) > 0 {
+		fields := make([]string, 0, 2*len(s.TimeZones))
+
+		for _, z := range s.TimeZones {
+			fields = append(fields, strconv.FormatUint(z.AdjustmentTime, 10), m.typedTime('z', z.Offset))
+		}
+
+		m.line('z', strings.Join(fields, " "))
+	}
+
+	if s.Key != "" {
+		m.line('k', s.Key)
+	}
+
+	for _, a := range s.Attributes {
+		m.attribute(&a)
+	}
+
+	for _, media := range s.Media {
+		m.media(&media)
+
+		if media.Title != "" {
+			m.line('i', media.Title)
+		}
+
+		for _, c := range media.Connections {
+			m.connection(&c)
+		}
+
+		for _, b := range media.Bandwidths {
+			m.bandwidth(&b)
+		}
+
+		if media.Key != "" {
+			m.line('k', media.Key)
+		}
+
+		for _, a := range media.Attributes {
+			m.attribute(&a)
+		}
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.buf.Bytes(), nil
+}
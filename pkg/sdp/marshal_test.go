@@ -0,0 +1,169 @@
+package sdp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSession_Marshal(t *testing.T) {
+	s, err := Parse([]byte(testSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	b, err := s.Marshal()
+	if err != nil {
+		t.Fatalf("Session.Marshal() error = %v", err)
+	}
+
+	if string(b) != testSDP {
+		t.Errorf("Session.Marshal() = %q, want %q", b, testSDP)
+	}
+}
+
+func TestSession_Marshal_roundTrip(t *testing.T) {
+	s := &Session{
+		Origin:      NewOrigin("jdoe", "3724394400", 3724394405, net.ParseIP("198.51.100.1")),
+		Name:        "Call to John Smith",
+		Information: "A Seminar on the session description protocol",
+		URI:         "http://www.example.com/seminars/sdp.pdf",
+		Emails:      []string{"j.doe@example.com (Jane Doe)"},
+		Connection: &Connection{
+			NetworkType:  NetworkTypeInternet,
+			AddressType:  AddressTypeIPv6,
+			Address:      "ff1e:3ad::7f2e:172a:1e24",
+			NumAddresses: 3,
+		},
+		Bandwidths: []Bandwidth{{Type: "AS", Value: 128}},
+		Timings: []Timing{{
+			Start: 3724394400,
+			Stop:  3724398000,
+			Repeats: []Repeat{{
+				Interval: 7 * 24 * time.Hour,
+				Duration: 90 * time.Minute,
+				Offsets:  []time.Duration{0, 25 * time.Hour, 61 * time.Second},
+			}},
+		}},
+		TimeZones: []TimeZone{
+			{AdjustmentTime: 2882844526, Offset: -time.Hour},
+			{AdjustmentTime: 2898848070, Offset: 0},
+		},
+		Attributes: Attributes{{Name: "recvonly"}},
+		Media: []Media{
+			{
+				Type:        "audio",
+				Port:        49170,
+				NumPorts:    2,
+				Protocol:    "RTP/AVP",
+				Formats:     []string{"0", "8"},
+				Connections: []Connection{NewConnection(net.ParseIP("239.0.0.1"), 32)},
+				Attributes:  Attributes{{Name: "rtpmap", Value: "0 PCMU/8000"}},
+			},
+		},
+	}
+
+	b, err := s.Marshal()
+	if err != nil {
+		t.Fatalf("Session.Marshal() error = %v", err)
+	}
+
+	got, err := Parse(b)
+	if err != nil {
+		t.Fatalf("Parse() error = %v\n%s", err, b)
+	}
+
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("Parse(Session.Marshal()) = %+v, want %+v", got, s)
+	}
+}
+
+func TestSession_Marshal_defaults(t *testing.T) {
+	s := &Session{
+		Origin: NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+	}
+
+	b, err := s.Marshal()
+	if err != nil {
+		t.Fatalf("Session.Marshal() error = %v", err)
+	}
+
+	want := "v=0\r\n" +
+		"o=- 1 1 IN IP4 192.0.2.1\r\n" +
+		"s= \r\n" +
+		"t=0 0\r\n"
+
+	if string(b) != want {
+		t.Errorf("Session.Marshal() = %q, want %q", b, want)
+	}
+}
+
+func TestSession_Marshal_errors(t *testing.T) {
+	s := &Session{
+		Origin: NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+		Name:   "x\r\na=injected",
+	}
+
+	if _, err := s.Marshal(); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Session.Marshal() error = %v, want %v", err, ErrInvalidValue)
+	}
+
+	invalid := []struct {
+		name    string
+		session Session
+	}{
+		{"origin with space", Session{Origin: NewOrigin("John Doe", "1", 1, net.ParseIP("192.0.2.1"))}},
+		{"empty origin field", Session{Origin: Origin{SessionID: "1", NetworkType: "IN", AddressType: "IP4", Address: "192.0.2.1"}}},
+		{"connection with space", Session{
+			Origin:     NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Connection: &Connection{NetworkType: "IN", AddressType: "IP4", Address: "239.0.0.1 x"},
+		}},
+		{"bandwidth type", Session{
+			Origin:     NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Bandwidths: []Bandwidth{{Type: "A:S", Value: 1}},
+		}},
+		{"media without formats", Session{
+			Origin: NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Media:  []Media{{Type: "audio", Port: 5004, Protocol: "RTP/AVP"}},
+		}},
+		{"media port", Session{
+			Origin: NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Media:  []Media{{Type: "audio", Port: 70000, Protocol: "RTP/AVP", Formats: []string{"98"}}},
+		}},
+		{"repeat without offsets", Session{
+			Origin:  NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Timings: []Timing{{Repeats: []Repeat{{Interval: time.Hour, Duration: time.Minute}}}},
+		}},
+		{"repeat with fractional seconds", Session{
+			Origin:  NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Timings: []Timing{{Repeats: []Repeat{{Interval: time.Hour, Duration: 1500 * time.Millisecond, Offsets: []time.Duration{0}}}}},
+		}},
+		{"time zone with fractional seconds", Session{
+			Origin:    NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			TimeZones: []TimeZone{{AdjustmentTime: 1, Offset: time.Millisecond}},
+		}},
+		{"IPv6 connection with TTL", Session{
+			Origin:     NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Connection: &Connection{NetworkType: "IN", AddressType: "IP6", Address: "ff0e::1", TTL: 32},
+		}},
+		{"attribute name", Session{
+			Origin:     NewOrigin("-", "1", 1, net.ParseIP("192.0.2.1")),
+			Attributes: Attributes{{Name: "a:b"}},
+		}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.session.Marshal(); !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("Session.Marshal() error = %v, want %v", err, ErrInvalidValue)
+			}
+		})
+	}
+
+	s = &Session{Version: 1}
+
+	if _, err := s.Marshal(); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Session.Marshal() error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
@@ -0,0 +1,552 @@
+package sdp
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidLine = errors.New("invalid line")
+var ErrUnknownField = errors.New("unknown field type")
+var ErrUnexpectedField = errors.New("field not allowed here")
+var ErrDuplicateField = errors.New("duplicate field")
+var ErrMissingField = errors.New("missing mandatory field")
+var ErrInvalidValue = errors.New("invalid value")
+var ErrUnsupportedVersion = errors.New("unsupported version")
+
+// ParseError reports the line a session description failed to parse at.
+// Line numbers start at 1. Errors about fields missing at the end of the
+// description carry the number of the last line.
+type ParseError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %q: %v", e.Line, e.Text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+type parser struct {
+	session *Session
+	media   *Media
+
+	line int
+	text string
+
+	seen map[byte]bool
+}
+
+func (p *parser) errorf(err error, format string, args ...interface{}) error {
+	return &ParseError{
+		Line: p.line,
+		Text: p.text,
+		Err:  errors.Wrapf(err, format, args...),
+	}
+}
+
+func (p *parser) error(err error) error {
+	return &ParseError{
+		Line: p.line,
+		Text: p.text,
+		Err:  err,
+	}
+}
+
+// Parse parses a session description as described in RFC 8866. Lines may be
+// terminated by CRLF or LF. Apart from v=, o= and s= which must come first,
+// the order of the fields within a section is not enforced.
+func Parse(b []byte) (*Session, error) {
+	p := &parser{
+		session: &Session{},
+		seen:    make(map[byte]bool),
+	}
+
+	lines := bytes.Split(b, []byte("\n"))
+
+	// A trailing line terminator does not start another line
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	for i, line := range lines {
+		p.line = i + 1
+		p.text = strings.TrimSuffix(string(line), "\r")
+
+		if err := p.parseLine(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, typ := range []byte{'v', 'o', 's', 't'} {
+		if !p.seen[typ] {
+			return nil, p.errorf(ErrMissingField, "%c=", typ)
+		}
+	}
+
+	return p.session, nil
+}
+
+func (p *parser) parseLine() error {
+	if len(p.text) < 2 || p.text[1] != '=' {
+		return p.error(ErrInvalidLine)
+	}
+
+	typ := p.text[0]
+	value := p.text[2:]
+
+	// RFC 8866, section 5:
+	// The first three fields are v=, o= and s=, in that order.
+	for _, mandatory := range []byte("vos") {
+		if p.seen[mandatory] {
+			continue
+		}
+
+		if typ != mandatory {
+			return p.errorf(ErrMissingField, "%c=", mandatory)
+		}
+
+		break
+	}
+
+	if p.media != nil {
+		return p.parseMediaLine(typ, value)
+	}
+
+	return p.parseSessionLine(typ, value)
+}
+
+func (p *parser) once(typ byte) error {
+	if p.seen[typ] {
+		return p.error(ErrDuplicateField)
+	}
+
+	p.seen[typ] = true
+
+	return nil
+}
+
+func (p *parser) parseSessionLine(typ byte, value string) error {
+	s := p.session
+
+	switch typ {
+	case 'v':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return p.error(ErrInvalidValue)
+		}
+
+		if version != 0 {
+			return p.error(ErrUnsupportedVersion)
+		}
+
+		s.Version = version
+
+	case 'o':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		return p.parseOrigin(value)
+
+	case 's':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		s.Name = value
+
+	case 'i':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		s.Information = value
+
+	case 'u':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		s.URI = value
+
+	case 'e':
+		s.Emails = append(s.Emails, value)
+
+	case 'p':
+		s.Phones = append(s.Phones, value)
+
+	case 'c':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		c, err := p.parseConnection(value)
+		if err != nil {
+			return err
+		}
+
+		s.Connection = c
+
+	case 'b':
+		b, err := p.parseBandwidth(value)
+		if err != nil {
+			return err
+		}
+
+		s.Bandwidths = append(s.Bandwidths, *b)
+
+	case 't':
+		p.seen[typ] = true
+
+		t, err := p.parseTiming(value)
+		if err != nil {
+			return err
+		}
+
+		s.Timings = append(s.Timings, *t)
+
+	case 'r':
+		if len(s.Timings) == 0 {
+			return p.errorf(ErrUnexpectedField, "r= before t=")
+		}
+
+		r, err := p.parseRepeat(value)
+		if err != nil {
+			return err
+		}
+
+		t := &s.Timings[len(s.Timings)-1]
+		t.Repeats = append(t.Repeats, *r)
+
+	case 'z':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		z, err := p.parseTimeZones(value)
+		if err != nil {
+			return err
+		}
+
+		s.TimeZones = z
+
+	case 'k':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		s.Key = value
+
+	case 'a':
+		s.Attributes = append(s.Attributes, parseAttribute(value))
+
+	case 'm':
+		if !p.seen['t'] {
+			return p.errorf(ErrMissingField, "t=")
+		}
+
+		return p.parseMedia(value)
+
+	default:
+		return p.error(ErrUnknownField)
+	}
+
+	return nil
+}
+
+func (p *parser) parseMediaLine(typ byte, value string) error {
+	m := p.media
+
+	switch typ {
+	case 'm':
+		return p.parseMedia(value)
+
+	case 'i':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		m.Title = value
+
+	case 'c':
+		c, err := p.parseConnection(value)
+		if err != nil {
+			return err
+		}
+
+		m.Connections = append(m.Connections, *c)
+
+	case 'b':
+		b, err := p.parseBandwidth(value)
+		if err != nil {
+			return err
+		}
+
+		m.Bandwidths = append(m.Bandwidths, *b)
+
+	case 'k':
+		if err := p.once(typ); err != nil {
+			return err
+		}
+
+		m.Key = value
+
+	case 'a':
+		m.Attributes = append(m.Attributes, parseAttribute(value))
+
+	case 'v', 'o', 's', 'u', 'e', 'p', 't', 'r', 'z':
+		return p.errorf(ErrUnexpectedField, "session level field in media description")
+
+	default:
+		return p.error(ErrUnknownField)
+	}
+
+	return nil
+}
+
+func (p *parser) parseOrigin(value string) error {
+	fields := strings.Split(value, " ")
+	if len(fields) != 6 {
+		return p.errorf(ErrInvalidValue, "expected 6 fields, got %d", len(fields))
+	}
+
+	version, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return p.errorf(ErrInvalidValue, "session version")
+	}
+
+	p.session.Origin = Origin{
+		Username:       fields[0],
+		SessionID:      fields[1],
+		SessionVersion: version,
+		NetworkType:    fields[3],
+		AddressType:    fields[4],
+		Address:        fields[5],
+	}
+
+	return nil
+}
+
+func (p *parser) parseConnection(value string) (*Connection, error) {
+	fields := strings.Split(value, " ")
+	if len(fields) != 3 {
+		return nil, p.errorf(ErrInvalidValue, "expected 3 fields, got %d", len(fields))
+	}
+
+	c := &Connection{
+		NetworkType: fields[0],
+		AddressType: fields[1],
+	}
+
+	parts := strings.Split(fields[2], "/")
+	c.Address = parts[0]
+
+	numbers := make([]int, 0, 2)
+
+	for _, part := range parts[1:] {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, p.errorf(ErrInvalidValue, "connection address")
+		}
+
+		numbers = append(numbers, n)
+	}
+
+	// RFC 8866, section 5.7:
+	// IPv4 multicast addresses carry a TTL, followed by an optional number
+	// of addresses. IPv6 addresses only carry the number of addresses.
+	switch {
+	case len(numbers) == 0:
+	case c.AddressType == AddressTypeIPv6 && len(numbers) == 1:
+		c.NumAddresses = numbers[0]
+	case c.AddressType != AddressTypeIPv6 && len(numbers) <= 2:
+		c.TTL = numbers[0]
+
+		if len(numbers) == 2 {
+			c.NumAddresses = numbers[1]
+		}
+	default:
+		return nil, p.errorf(ErrInvalidValue, "connection address")
+	}
+
+	return c, nil
+}
+
+func (p *parser) parseBandwidth(value string) (*Bandwidth, error) {
+	typ, bw, ok := strings.Cut(value, ":")
+	if !ok || typ == "" {
+		return nil, p.errorf(ErrInvalidValue, "bandwidth")
+	}
+
+	n, err := strconv.ParseUint(bw, 10, 64)
+	if err != nil {
+		return nil, p.errorf(ErrInvalidValue, "bandwidth")
+	}
+
+	return &Bandwidth{
+		Type:  typ,
+		Value: n,
+	}, nil
+}
+
+func (p *parser) parseTiming(value string) (*Timing, error) {
+	fields := strings.Split(value, " ")
+	if len(fields) != 2 {
+		return nil, p.errorf(ErrInvalidValue, "expected 2 fields, got %d", len(fields))
+	}
+
+	start, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return nil, p.errorf(ErrInvalidValue, "start time")
+	}
+
+	stop, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, p.errorf(ErrInvalidValue, "stop time")
+	}
+
+	return &Timing{
+		Start: start,
+		Stop:  stop,
+	}, nil
+}
+
+func (p *parser) parseRepeat(value string) (*Repeat, error) {
+	fields := strings.Split(value, " ")
+	if len(fields) < 3 {
+		return nil, p.errorf(ErrInvalidValue, "expected at least 3 fields, got %d", len(fields))
+	}
+
+	durations := make([]time.Duration, 0, len(fields))
+
+	for _, f := range fields {
+		d, err := parseTypedTime(f)
+		if err != nil {
+			return nil, p.errorf(ErrInvalidValue, "%q", f)
+		}
+
+		durations = append(durations, d)
+	}
+
+	return &Repeat{
+		Interval: durations[0],
+		Duration: durations[1],
+		Offsets:  durations[2:],
+	}, nil
+}
+
+func (p *parser) parseTimeZones(value string) ([]TimeZone, error) {
+	fields := strings.Split(value, " ")
+	if len(fields)%2 != 0 {
+		return nil, p.errorf(ErrInvalidValue, "expected pairs of adjustment time and offset")
+	}
+
+	zones := make([]TimeZone, 0, len(fields)/2)
+
+	for i := 0; i < len(fields); i += 2 {
+		t, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, p.errorf(ErrInvalidValue, "%q", fields[i])
+		}
+
+		offset, err := parseTypedTime(fields[i+1])
+		if err != nil {
+			return nil, p.errorf(ErrInvalidValue, "%q", fields[i+1])
+		}
+
+		zones = append(zones, TimeZone{
+			AdjustmentTime: t,
+			Offset:         offset,
+		})
+	}
+
+	return zones, nil
+}
+
+func (p *parser) parseMedia(value string) error {
+	fields := strings.Split(value, " ")
+	if len(fields) < 4 {
+		return p.errorf(ErrInvalidValue, "expected at least 4 fields, got %d", len(fields))
+	}
+
+	m := Media{
+		Type:     fields[0],
+		Protocol: fields[2],
+		Formats:  fields[3:],
+	}
+
+	port, numPorts, hasNumPorts := strings.Cut(fields[1], "/")
+
+	var err error
+
+	m.Port, err = strconv.Atoi(port)
+	if err != nil || m.Port < 0 || m.Port > 0xffff {
+		return p.errorf(ErrInvalidValue, "port")
+	}
+
+	if hasNumPorts {
+		m.NumPorts, err = strconv.Atoi(numPorts)
+		if err != nil || m.NumPorts < 1 {
+			return p.errorf(ErrInvalidValue, "number of ports")
+		}
+	}
+
+	p.session.Media = append(p.session.Media, m)
+	p.media = &p.session.Media[len(p.session.Media)-1]
+
+	// Fields that may appear once do so per media description
+	for _, typ := range []byte{'i', 'k'} {
+		delete(p.seen, typ)
+	}
+
+	return nil
+}
+
+func parseAttribute(value string) Attribute {
+	name, v, _ := strings.Cut(value, ":")
+
+	return Attribute{
+		Name:  name,
+		Value: v,
+	}
+}
+
+var typedTimeUnits = map[byte]time.Duration{
+	'd': 24 * time.Hour,
+	'h': time.Hour,
+	'm': time.Minute,
+	's': time.Second,
+}
+
+// RFC 8866, section 5.10:
+// Times may be given in seconds or with a unit suffix of d, h, m or s.
+func parseTypedTime(s string) (time.Duration, error) {
+	unit := time.Second
+
+	if len(s) > 0 {
+		if u, ok := typedTimeUnits[s[len(s)-1]]; ok {
+			unit = u
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,249 @@
+package sdp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The payload of the announcement in the tests of package sap
+const testSDP = "v=0\r\n" +
+	"o=- 02844247180001 0 IN IP4 192.168.100.254\r\n" +
+	"s=TX-1-DNT1-8\r\n" +
+	"t=0 0\r\n" +
+	"a=clock-domain:PTPv2 0\r\n" +
+	"a=ts-refclk:ptp=IEEE1588-2008:C8-0D-32-FF-FE-4C-85-42:0\r\n" +
+	"a=mediaclk:direct=0\r\n" +
+	"a=group:DUP ra0 ra1\r\n" +
+	"m=audio 5004 RTP/AVP 98\r\n" +
+	"c=IN IP4 239.100.254.1/5\r\n" +
+	"a=source-filter: incl IN IP4 239.100.254.1 192.168.100.254\r\n" +
+	"a=rtpmap:98 L24/48000/8\r\n" +
+	"a=mid:ra0\r\n" +
+	"a=framecount:6\r\n" +
+	"a=recvonly\r\n" +
+	"a=ptime:0.125\r\n" +
+	"a=sync-time:0\r\n" +
+	"a=mediaclk:direct=0\r\n" +
+	"m=audio 5004 RTP/AVP 98\r\n" +
+	"c=IN IP4 239.200.254.1/5\r\n" +
+	"a=source-filter: incl IN IP4 239.200.254.1 192.168.200.254\r\n" +
+	"a=rtpmap:98 L24/48000/8\r\n" +
+	"a=mid:ra1\r\n" +
+	"a=framecount:6\r\n" +
+	"a=recvonly\r\n" +
+	"a=ptime:0.125\r\n" +
+	"a=sync-time:0\r\n" +
+	"a=mediaclk:direct=0\r\n"
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(testSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	wantOrigin := Origin{
+		Username:       "-",
+		SessionID:      "02844247180001",
+		SessionVersion: 0,
+		NetworkType:    NetworkTypeInternet,
+		AddressType:    AddressTypeIPv4,
+		Address:        "192.168.100.254",
+	}
+
+	if s.Origin != wantOrigin {
+		t.Errorf("Session.Origin = %+v, want %+v", s.Origin, wantOrigin)
+	}
+
+	if s.Name != "TX-1-DNT1-8" {
+		t.Errorf("Session.Name = %q, want %q", s.Name, "TX-1-DNT1-8")
+	}
+
+	if !reflect.DeepEqual(s.Timings, []Timing{{}}) {
+		t.Errorf("Session.Timings = %+v, want one unbounded timing", s.Timings)
+	}
+
+	if v, _ := s.Attributes.Get("group"); v != "DUP ra0 ra1" {
+		t.Errorf("group attribute = %q, want %q", v, "DUP ra0 ra1")
+	}
+
+	if len(s.Media) != 2 {
+		t.Fatalf("len(Session.Media) = %d, want 2", len(s.Media))
+	}
+
+	m := s.Media[1]
+
+	if m.Type != "audio" || m.Port != 5004 || m.Protocol != "RTP/AVP" || !reflect.DeepEqual(m.Formats, []string{"98"}) {
+		t.Errorf("Media = %+v, want audio 5004 RTP/AVP 98", m)
+	}
+
+	wantConnection := Connection{
+		NetworkType: NetworkTypeInternet,
+		AddressType: AddressTypeIPv4,
+		Address:     "239.200.254.1",
+		TTL:         5,
+	}
+
+	if !reflect.DeepEqual(m.Connections, []Connection{wantConnection}) {
+		t.Errorf("Media.Connections = %+v, want %+v", m.Connections, wantConnection)
+	}
+
+	if !m.Attributes.Has("recvonly") {
+		t.Errorf("Media.Attributes has no recvonly")
+	}
+
+	if v, _ := m.Attributes.Get("mid"); v != "ra1" {
+		t.Errorf("mid attribute = %q, want %q", v, "ra1")
+	}
+}
+
+func TestParse_timing(t *testing.T) {
+	s, err := Parse([]byte("v=0\n" +
+		"o=jdoe 3724394400 3724394405 IN IP4 198.51.100.1\n" +
+		"s=Call to John Smith\n" +
+		"c=IN IP6 ff1e:03ad::7F2E:172a:1e24/3\n" +
+		"b=AS:128\n" +
+		"t=3724394400 3724398000\n" +
+		"r=7d 1h 0 25h\n" +
+		"z=2882844526 -1h 2898848070 0\n" +
+		"m=audio 49170/2 RTP/AVP 0 8\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	wantTimings := []Timing{{
+		Start: 3724394400,
+		Stop:  3724398000,
+		Repeats: []Repeat{{
+			Interval: 7 * 24 * time.Hour,
+			Duration: time.Hour,
+			Offsets:  []time.Duration{0, 25 * time.Hour},
+		}},
+	}}
+
+	if !reflect.DeepEqual(s.Timings, wantTimings) {
+		t.Errorf("Session.Timings = %+v, want %+v", s.Timings, wantTimings)
+	}
+
+	wantTimeZones := []TimeZone{
+		{AdjustmentTime: 2882844526, Offset: -time.Hour},
+		{AdjustmentTime: 2898848070, Offset: 0},
+	}
+
+	if !reflect.DeepEqual(s.TimeZones, wantTimeZones) {
+		t.Errorf("Session.TimeZones = %+v, want %+v", s.TimeZones, wantTimeZones)
+	}
+
+	if s.Connection.NumAddresses != 3 || s.Connection.TTL != 0 {
+		t.Errorf("Session.Connection = %+v, want 3 addresses without TTL", s.Connection)
+	}
+
+	if !reflect.DeepEqual(s.Bandwidths, []Bandwidth{{Type: "AS", Value: 128}}) {
+		t.Errorf("Session.Bandwidths = %+v, want AS:128", s.Bandwidths)
+	}
+
+	if m := s.Media[0]; m.NumPorts != 2 || !reflect.DeepEqual(m.Formats, []string{"0", "8"}) {
+		t.Errorf("Media = %+v, want 2 ports and formats 0 8", m)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		sdp      string
+		wantLine int
+		wantErr  error
+	}{
+		{
+			name:     "empty",
+			sdp:      "",
+			wantLine: 0,
+			wantErr:  ErrMissingField,
+		},
+		{
+			name:     "origin first",
+			sdp:      "o=- 1 1 IN IP4 192.0.2.1\r\nv=0\r\n",
+			wantLine: 1,
+			wantErr:  ErrMissingField,
+		},
+		{
+			name:     "version",
+			sdp:      "v=1\r\n",
+			wantLine: 1,
+			wantErr:  ErrUnsupportedVersion,
+		},
+		{
+			name:     "short origin",
+			sdp:      "v=0\r\no=- 1 1 IN IP4\r\ns=x\r\nt=0 0\r\n",
+			wantLine: 2,
+			wantErr:  ErrInvalidValue,
+		},
+		{
+			name:     "missing timing",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\nm=audio 5004 RTP/AVP 98\r\n",
+			wantLine: 4,
+			wantErr:  ErrMissingField,
+		},
+		{
+			name:     "missing timing at end",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\n",
+			wantLine: 3,
+			wantErr:  ErrMissingField,
+		},
+		{
+			name:     "garbage",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\nt=0 0\r\nhello\r\n",
+			wantLine: 5,
+			wantErr:  ErrInvalidLine,
+		},
+		{
+			name:     "unknown field",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\nt=0 0\r\nx=1\r\n",
+			wantLine: 5,
+			wantErr:  ErrUnknownField,
+		},
+		{
+			name:     "bad port",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\nt=0 0\r\nm=audio port RTP/AVP 98\r\n",
+			wantLine: 5,
+			wantErr:  ErrInvalidValue,
+		},
+		{
+			name:     "session field in media",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\nt=0 0\r\nm=audio 5004 RTP/AVP 98\r\nt=0 0\r\n",
+			wantLine: 6,
+			wantErr:  ErrUnexpectedField,
+		},
+		{
+			name:     "duplicate name",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\ns=y\r\n",
+			wantLine: 4,
+			wantErr:  ErrDuplicateField,
+		},
+		{
+			name:     "bad connection",
+			sdp:      "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=x\r\nc=IN IP4 239.0.0.1/a\r\n",
+			wantLine: 4,
+			wantErr:  ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.sdp))
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse() error = %v, want *ParseError", err)
+			}
+
+			if parseErr.Line != tt.wantLine {
+				t.Errorf("ParseError.Line = %d, want %d", parseErr.Line, tt.wantLine)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package sdp
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GroupSemanticsDuplication marks media descriptions that carry the same
+// stream on separate paths (RFC 7104), as used for SMPTE ST 2022-7.
+const GroupSemanticsDuplication = "DUP"
+
+// StreamLeg is one path of a redundant stream.
+type StreamLeg struct {
+	ID            string
+	Media         *Media
+	Destination   net.IP
+	Port          int
+	SourceFilters []SourceFilter
+}
+
+// RedundantStream is a pair of legs carrying the same stream. The primary
+// leg is the one listed first in the group.
+type RedundantStream struct {
+	Primary   StreamLeg
+	Secondary StreamLeg
+}
+
+// Groups returns the media IDs of all a=group attributes with the given
+// semantics (RFC 5888).
+func (s *Session) Groups(semantics string) [][]string {
+	groups := make([][]string, 0)
+
+	for _, v := range s.Attributes.GetAll("group") {
+		fields := strings.Fields(v)
+		if len(fields) > 0 && fields[0] == semantics {
+			groups = append(groups, fields[1:])
+		}
+	}
+
+	return groups
+}
+
+// MediaByID returns the media description with the given a=mid, or nil.
+func (s *Session) MediaByID(id string) *Media {
+	for i := range s.Media {
+		if mid, _ := s.Media[i].Attributes.Get("mid"); mid == id {
+			return &s.Media[i]
+		}
+	}
+
+	return nil
+}
+
+// StreamLeg returns the addressing of the given media description, which must
+// belong to the session.
+func (s *Session) StreamLeg(m *Media) (StreamLeg, error) {
+	leg := StreamLeg{
+		Media: m,
+		Port:  m.Port,
+	}
+
+	leg.ID, _ = m.Attributes.Get("mid")
+
+	if c := s.connection(m); c != nil {
+		leg.Destination = c.IP()
+	}
+
+	filters, err := s.SourceFilters(m)
+	if err != nil {
+		return leg, err
+	}
+
+	leg.SourceFilters = filters
+
+	return leg, nil
+}
+
+// RedundantStreams resolves the a=group:DUP attributes of the session into
+// pairs of media descriptions. Groups with more than two media IDs use the
+// first two.
+func (s *Session) RedundantStreams() ([]RedundantStream, error) {
+	streams := make([]RedundantStream, 0)
+
+	for _, ids := range s.Groups(GroupSemanticsDuplication) {
+		if len(ids) < 2 {
+			return nil, errors.Wrapf(ErrInvalidValue, "DUP group with %d media", len(ids))
+		}
+
+		var legs [2]StreamLeg
+
+		for i, id := range ids[:2] {
+			m := s.MediaByID(id)
+			if m == nil {
+				return nil, errors.Wrapf(ErrMissingField, "media with mid %q", id)
+			}
+
+			leg, err := s.StreamLeg(m)
+			if err != nil {
+				return nil, err
+			}
+
+			legs[i] = leg
+		}
+
+		streams = append(streams, RedundantStream{
+			Primary:   legs[0],
+			Secondary: legs[1],
+		})
+	}
+
+	return streams, nil
+}
@@ -0,0 +1,55 @@
+package sdp
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestSession_RedundantStreams(t *testing.T) {
+	s, err := Parse([]byte(testSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	streams, err := s.RedundantStreams()
+	if err != nil {
+		t.Fatalf("Session.RedundantStreams() error = %v", err)
+	}
+
+	if len(streams) != 1 {
+		t.Fatalf("len(Session.RedundantStreams()) = %d, want 1", len(streams))
+	}
+
+	primary, secondary := streams[0].Primary, streams[0].Secondary
+
+	if primary.ID != "ra0" || !primary.Destination.Equal(net.ParseIP("239.100.254.1")) || primary.Port != 5004 {
+		t.Errorf("RedundantStream.Primary = %+v, want ra0 239.100.254.1:5004", primary)
+	}
+
+	if secondary.ID != "ra1" || !secondary.Destination.Equal(net.ParseIP("239.200.254.1")) || secondary.Media != &s.Media[1] {
+		t.Errorf("RedundantStream.Secondary = %+v, want ra1 239.200.254.1", secondary)
+	}
+
+	if len(secondary.SourceFilters) != 1 || secondary.SourceFilters[0].Sources[0] != "192.168.200.254" {
+		t.Errorf("RedundantStream.Secondary.SourceFilters = %+v, want source 192.168.200.254", secondary.SourceFilters)
+	}
+}
+
+func TestSession_RedundantStreams_errors(t *testing.T) {
+	for _, group := range []string{"DUP ra0", "DUP ra0 ra2"} {
+		s := &Session{
+			Attributes: Attributes{{Name: "group", Value: group}},
+			Media: []Media{
+				{Type: "audio", Attributes: Attributes{{Name: "mid", Value: "ra0"}}},
+				{Type: "audio", Attributes: Attributes{{Name: "mid", Value: "ra1"}}},
+			},
+		}
+
+		if _, err := s.RedundantStreams(); err == nil {
+			t.Errorf("Session.RedundantStreams() with %q succeeded", group)
+		} else if !errors.Is(err, ErrInvalidValue) && !errors.Is(err, ErrMissingField) {
+			t.Errorf("Session.RedundantStreams() error = %v", err)
+		}
+	}
+}
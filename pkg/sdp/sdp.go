@@ -0,0 +1,136 @@
+package sdp
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	NetworkTypeInternet = "IN"
+	AddressTypeIPv4     = "IP4"
+	AddressTypeIPv6     = "IP6"
+)
+
+type Origin struct {
+	Username       string
+	SessionID      string
+	SessionVersion uint64
+	NetworkType    string
+	AddressType    string
+	Address        string
+}
+
+// Identity returns the globally unique identifier of the session formed by all
+// origin fields but the version (RFC 8866, section 5.2). It stays the same
+// when a session is modified.
+func (o *Origin) Identity() string {
+	return strings.Join([]string{o.Username, o.SessionID, o.NetworkType, o.AddressType, o.Address}, " ")
+}
+
+// Connection is the content of a c= line. TTL and NumAddresses are zero if
+// they are not present. Only IPv4 addresses carry a TTL.
+type Connection struct {
+	NetworkType  string
+	AddressType  string
+	Address      string
+	TTL          int
+	NumAddresses int
+}
+
+// IP returns the connection address, or nil if it is not an IP address.
+func (c *Connection) IP() net.IP {
+	return net.ParseIP(c.Address)
+}
+
+type Bandwidth struct {
+	Type  string
+	Value uint64
+}
+
+// Timing is the content of a t= line along with its r= lines. Start and Stop
+// are NTP timestamps in seconds, zero meaning unbounded.
+type Timing struct {
+	Start   uint64
+	Stop    uint64
+	Repeats []Repeat
+}
+
+type Repeat struct {
+	Interval time.Duration
+	Duration time.Duration
+	Offsets  []time.Duration
+}
+
+type TimeZone struct {
+	AdjustmentTime uint64
+	Offset         time.Duration
+}
+
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+type Attributes []Attribute
+
+// Get returns the value of the first attribute with the given name.
+func (a Attributes) Get(name string) (string, bool) {
+	for _, attr := range a {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// GetAll returns the values of all attributes with the given name.
+func (a Attributes) GetAll(name string) []string {
+	values := make([]string, 0)
+
+	for _, attr := range a {
+		if attr.Name == name {
+			values = append(values, attr.Value)
+		}
+	}
+
+	return values
+}
+
+func (a Attributes) Has(name string) bool {
+	_, ok := a.Get(name)
+
+	return ok
+}
+
+type Media struct {
+	Type        string
+	Port        int
+	NumPorts    int
+	Protocol    string
+	Formats     []string
+	Title       string
+	Connections []Connection
+	Bandwidths  []Bandwidth
+	Key         string
+	Attributes  Attributes
+}
+
+// Session is a session description as described in RFC 8866.
+type Session struct {
+	Version     int
+	Origin      Origin
+	Name        string
+	Information string
+	URI         string
+	Emails      []string
+	Phones      []string
+	Connection  *Connection
+	Bandwidths  []Bandwidth
+	Timings     []Timing
+	TimeZones   []TimeZone
+	Key         string
+	Attributes  Attributes
+	Media       []Media
+}
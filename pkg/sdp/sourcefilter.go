@@ -0,0 +1,117 @@
+package sdp
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type FilterMode string
+
+const (
+	FilterModeInclude FilterMode = "incl"
+	FilterModeExclude FilterMode = "excl"
+)
+
+// Wildcard may be used as address type or destination of a source filter.
+const Wildcard = "*"
+
+// SourceFilter is the content of an a=source-filter attribute as described in
+// RFC 4570.
+type SourceFilter struct {
+	Mode        FilterMode
+	NetworkType string
+	AddressType string
+	Destination string
+	Sources     []string
+}
+
+// ParseSourceFilter parses the value of an a=source-filter attribute.
+//
+// RFC 4570, section 3:
+// a=source-filter: <filter-mode> <nettype> <address-types> <dest-address> <src-list>
+func ParseSourceFilter(value string) (*SourceFilter, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 5 {
+		return nil, errors.Wrapf(ErrInvalidValue, "source-filter %q", value)
+	}
+
+	f := &SourceFilter{
+		Mode:        FilterMode(fields[0]),
+		NetworkType: fields[1],
+		AddressType: fields[2],
+		Destination: fields[3],
+		Sources:     fields[4:],
+	}
+
+	if f.Mode != FilterModeInclude && f.Mode != FilterModeExclude {
+		return nil, errors.Wrapf(ErrInvalidValue, "source-filter mode %q", fields[0])
+	}
+
+	return f, nil
+}
+
+func (f *SourceFilter) String() string {
+	return strings.Join(append([]string{
+		string(f.Mode),
+		f.NetworkType,
+		f.AddressType,
+		f.Destination,
+	}, f.Sources...), " ")
+}
+
+// Attribute returns the filter as attribute to be added to a session or media
+// description. The value is written with a leading space as in the examples
+// of RFC 4570.
+func (f *SourceFilter) Attribute() Attribute {
+	return Attribute{
+		Name:  "source-filter",
+		Value: " " + f.String(),
+	}
+}
+
+// Matches reports whether the filter applies to traffic sent to dest.
+func (f *SourceFilter) Matches(dest net.IP) bool {
+	return f.Destination == Wildcard || dest.Equal(net.ParseIP(f.Destination))
+}
+
+// SourceIPs returns the sources that are IP addresses. Sources given as
+// domain names are skipped.
+func (f *SourceFilter) SourceIPs() []net.IP {
+	ips := make([]net.IP, 0, len(f.Sources))
+
+	for _, s := range f.Sources {
+		if ip := net.ParseIP(s); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+func parseSourceFilters(attributes Attributes) ([]SourceFilter, error) {
+	filters := make([]SourceFilter, 0)
+
+	for _, v := range attributes.GetAll("source-filter") {
+		f, err := ParseSourceFilter(v)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, *f)
+	}
+
+	return filters, nil
+}
+
+// SourceFilters returns the source filters in effect for the given media
+// description, or the session-level filters if m is nil. Following RFC 4570,
+// section 3.2, filters of a media description replace those of the session.
+func (s *Session) SourceFilters(m *Media) ([]SourceFilter, error) {
+	if m != nil && m.Attributes.Has("source-filter") {
+		return parseSourceFilters(m.Attributes)
+	}
+
+	return parseSourceFilters(s.Attributes)
+}
@@ -0,0 +1,94 @@
+package sdp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestSession_SourceFilters(t *testing.T) {
+	s, err := Parse([]byte(testSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	filters, err := s.SourceFilters(&s.Media[1])
+	if err != nil {
+		t.Fatalf("Session.SourceFilters() error = %v", err)
+	}
+
+	want := []SourceFilter{{
+		Mode:        FilterModeInclude,
+		NetworkType: NetworkTypeInternet,
+		AddressType: AddressTypeIPv4,
+		Destination: "239.200.254.1",
+		Sources:     []string{"192.168.200.254"},
+	}}
+
+	if !reflect.DeepEqual(filters, want) {
+		t.Fatalf("Session.SourceFilters() = %+v, want %+v", filters, want)
+	}
+
+	f := filters[0]
+
+	if !f.Matches(net.ParseIP("239.200.254.1")) || f.Matches(net.ParseIP("239.100.254.1")) {
+		t.Errorf("SourceFilter.Matches() does not match the destination only")
+	}
+
+	if ips := f.SourceIPs(); len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.168.200.254")) {
+		t.Errorf("SourceFilter.SourceIPs() = %v, want [192.168.200.254]", ips)
+	}
+
+	if a := f.Attribute(); !reflect.DeepEqual(a, s.Media[1].Attributes[0]) {
+		t.Errorf("SourceFilter.Attribute() = %+v, want %+v", a, s.Media[1].Attributes[0])
+	}
+}
+
+func TestSession_SourceFilters_inherited(t *testing.T) {
+	s, err := Parse([]byte("v=0\r\n" +
+		"o=- 1 1 IN IP4 192.0.2.1\r\n" +
+		"s=x\r\n" +
+		"t=0 0\r\n" +
+		"a=source-filter: excl IN * * 192.0.2.10 example.com\r\n" +
+		"m=audio 5004 RTP/AVP 98\r\n" +
+		"m=audio 5006 RTP/AVP 98\r\n" +
+		"a=source-filter: incl IN IP4 239.0.0.2 192.0.2.2\r\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	filters, err := s.SourceFilters(&s.Media[0])
+	if err != nil {
+		t.Fatalf("Session.SourceFilters() error = %v", err)
+	}
+
+	if len(filters) != 1 || filters[0].Mode != FilterModeExclude || !filters[0].Matches(net.ParseIP("239.0.0.1")) {
+		t.Errorf("Session.SourceFilters() = %+v, want the session-level wildcard filter", filters)
+	}
+
+	if ips := filters[0].SourceIPs(); len(ips) != 1 {
+		t.Errorf("SourceFilter.SourceIPs() = %v, want the address only", ips)
+	}
+
+	filters, err = s.SourceFilters(&s.Media[1])
+	if err != nil {
+		t.Fatalf("Session.SourceFilters() error = %v", err)
+	}
+
+	if len(filters) != 1 || filters[0].Mode != FilterModeInclude {
+		t.Errorf("Session.SourceFilters() = %+v, want the media-level filter", filters)
+	}
+}
+
+func TestParseSourceFilter_errors(t *testing.T) {
+	for _, v := range []string{
+		"",
+		" incl IN IP4 239.0.0.1",
+		" only IN IP4 239.0.0.1 192.0.2.1",
+	} {
+		if _, err := ParseSourceFilter(v); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("ParseSourceFilter(%q) error = %v, want %v", v, err, ErrInvalidValue)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FrameRate is an exact frame rate such as 30000/1001. Denominator is 1 for
+// integer rates.
+type FrameRate struct {
+	Numerator   int
+	Denominator int
+}
+
+// ParseFrameRate parses an exactframerate value ("25" or "30000/1001").
+func ParseFrameRate(s string) (FrameRate, error) {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		den = "1"
+	}
+
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		return FrameRate{}, errors.Wrapf(ErrInvalidValue, "frame rate %q", s)
+	}
+
+	d, err := strconv.Atoi(den)
+	if err != nil || d <= 0 {
+		return FrameRate{}, errors.Wrapf(ErrInvalidValue, "frame rate %q", s)
+	}
+
+	return FrameRate{Numerator: n, Denominator: d}, nil
+}
+
+// String writes integer rates without denominator, as SMPTE ST 2110-20,
+// section 7.2 requires.
+func (r FrameRate) String() string {
+	if r.Denominator == 1 {
+		return strconv.Itoa(r.Numerator)
+	}
+
+	return fmt.Sprintf("%d/%d", r.Numerator, r.Denominator)
+}
+
+// RawVideoFormat holds the a=fmtp parameters of an uncompressed video stream
+// as defined by SMPTE ST 2110-20, section 7. Parameters that are not covered
+// by the struct are kept in Extra.
+type RawVideoFormat struct {
+	Sampling       string
+	Width          int
+	Height         int
+	ExactFrameRate FrameRate
+	Depth          int
+	Colorimetry    string
+	TCS            string
+	PM             string
+	SSN            string
+	TP             string
+	Range          string
+	PAR            string
+	Interlace      bool
+	Segmented      bool
+	TROFF          int
+	CMAX           int
+	MAXUDP         int
+	Extra          FormatParameters
+}
+
+// CompressedVideoFormat holds the a=fmtp parameters of a compressed video
+// stream as defined by SMPTE ST 2110-22, section 7, along with the common
+// parameters of the JPEG XS payload format (RFC 9134).
+type CompressedVideoFormat struct {
+	Width          int
+	Height         int
+	ExactFrameRate FrameRate
+	TP             string
+	SSN            string
+	Profile        string
+	Level          string
+	Sublevel       string
+	Sampling       string
+	Depth          int
+	Colorimetry    string
+	TCS            string
+	Range          string
+	Interlace      bool
+	Segmented      bool
+	TROFF          int
+	CMAX           int
+	Extra          FormatParameters
+}
+
+// DIDSDID is a data identifier pair of SMPTE ST 291-1 ancillary data.
+type DIDSDID struct {
+	DID  uint8
+	SDID uint8
+}
+
+// RFC 8331, section 3.1:
+// DID_SDID={0x61,0x02}
+func parseDIDSDID(s string) (DIDSDID, error) {
+	inner, ok := strings.CutPrefix(s, "{")
+	if ok {
+		inner, ok = strings.CutSuffix(inner, "}")
+	}
+
+	did, sdid, found := strings.Cut(inner, ",")
+	if !ok || !found {
+		return DIDSDID{}, errors.Wrapf(ErrInvalidValue, "DID_SDID %q", s)
+	}
+
+	d, err := strconv.ParseUint(strings.TrimSpace(did), 0, 8)
+	if err != nil {
+		return DIDSDID{}, errors.Wrapf(ErrInvalidValue, "DID_SDID %q", s)
+	}
+
+	sd, err := strconv.ParseUint(strings.TrimSpace(sdid), 0, 8)
+	if err != nil {
+		return DIDSDID{}, errors.Wrapf(ErrInvalidValue, "DID_SDID %q", s)
+	}
+
+	return DIDSDID{DID: uint8(d), SDID: uint8(sd)}, nil
+}
+
+func (d DIDSDID) String() string {
+	return fmt.Sprintf("{0x%02X,0x%02X}", d.DID, d.SDID)
+}
+
+// AncillaryFormat holds the a=fmtp parameters of an ancillary data stream as
+// defined by SMPTE ST 2110-40 and RFC 8331.
+type AncillaryFormat struct {
+	DIDSDIDs       []DIDSDID
+	VPIDCode       int
+	ExactFrameRate FrameRate
+	SSN            string
+	TM             string
+	TROFF          int
+	Extra          FormatParameters
+}
+
+// fmtpReader extracts typed parameters and remembers which ones were used, so
+// the rest can be kept as extra parameters.
+type fmtpReader struct {
+	params FormatParameters
+	used   map[string]bool
+	err    error
+}
+
+func newFmtpReader(params FormatParameters) *fmtpReader {
+	return &fmtpReader{
+		params: params,
+		used:   make(map[string]bool),
+	}
+}
+
+func (r *fmtpReader) string(name string, required bool) string {
+	r.used[name] = true
+
+	v, ok := r.params.Get(name)
+	if !ok && required && r.err == nil {
+		r.err = errors.Wrapf(ErrMissingField, "fmtp parameter %s", name)
+	}
+
+	return v
+}
+
+func (r *fmtpReader) int(name string, required bool) int {
+	v := r.string(name, required)
+	if v == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil && r.err == nil {
+		r.err = errors.Wrapf(ErrInvalidValue, "fmtp parameter %s=%s", name, v)
+	}
+
+	return n
+}
+
+func (r *fmtpReader) frameRate(name string, required bool) FrameRate {
+	v := r.string(name, required)
+	if v == "" {
+		return FrameRate{}
+	}
+
+	rate, err := ParseFrameRate(v)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+
+	return rate
+}
+
+func (r *fmtpReader) flag(name string) bool {
+	r.used[name] = true
+
+	return r.params.Has(name)
+}
+
+func (r *fmtpReader) extra() FormatParameters {
+	extra := make(FormatParameters, 0)
+
+	for _, p := range r.params {
+		if !r.used[p.Name] {
+			extra = append(extra, p)
+		}
+	}
+
+	return extra
+}
+
+type fmtpWriter struct {
+	params FormatParameters
+}
+
+func (w *fmtpWriter) string(name, v string) {
+	if v != "" {
+		w.params = append(w.params, FormatParameter{Name: name, Value: v})
+	}
+}
+
+func (w *fmtpWriter) int(name string, v int) {
+	if v != 0 {
+		w.string(name, strconv.Itoa(v))
+	}
+}
+
+func (w *fmtpWriter) frameRate(name string, v FrameRate) {
+	if v.Numerator != 0 {
+		w.string(name, v.String())
+	}
+}
+
+func (w *fmtpWriter) flag(name string, v bool) {
+	if v {
+		w.params = append(w.params, FormatParameter{Name: name})
+	}
+}
+
+// ParseRawVideoFormat reads the parameters of a SMPTE ST 2110-20 stream. The
+// parameters the standard requires must be present, except TCS which defaults
+// to SDR.
+func ParseRawVideoFormat(params FormatParameters) (*RawVideoFormat, error) {
+	r := newFmtpReader(params)
+
+	f := &RawVideoFormat{
+		Sampling:       r.string("sampling", true),
+		Width:          r.int("width", true),
+		Height:         r.int("height", true),
+		ExactFrameRate: r.frameRate("exactframerate", true),
+		Depth:          r.int("depth", true),
+		Colorimetry:    r.string("colorimetry", true),
+		TCS:            r.string("TCS", false),
+		PM:             r.string("PM", true),
+		SSN:            r.string("SSN", true),
+		TP:             r.string("TP", false),
+		Range:          r.string("RANGE", false),
+		PAR:            r.string("PAR", false),
+		Interlace:      r.flag("interlace"),
+		Segmented:      r.flag("segmented"),
+		TROFF:          r.int("TROFF", false),
+		CMAX:           r.int("CMAX", false),
+		MAXUDP:         r.int("MAXUDP", false),
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if f.TCS == "" {
+		f.TCS = "SDR"
+	}
+
+	f.Extra = r.extra()
+
+	return f, nil
+}
+
+// FormatParameters returns the parameters in the order of the examples in
+// SMPTE ST 2110-20, followed by the extra parameters.
+func (f *RawVideoFormat) FormatParameters() FormatParameters {
+	w := &fmtpWriter{}
+
+	w.string("sampling", f.Sampling)
+	w.int("width", f.Width)
+	w.int("height", f.Height)
+	w.frameRate("exactframerate", f.ExactFrameRate)
+	w.int("depth", f.Depth)
+	w.string("TCS", f.TCS)
+	w.string("colorimetry", f.Colorimetry)
+	w.string("PM", f.PM)
+	w.string("SSN", f.SSN)
+	w.string("TP", f.TP)
+	w.string("RANGE", f.Range)
+	w.string("PAR", f.PAR)
+	w.flag("interlace", f.Interlace)
+	w.flag("segmented", f.Segmented)
+	w.int("TROFF", f.TROFF)
+	w.int("CMAX", f.CMAX)
+	w.int("MAXUDP", f.MAXUDP)
+
+	return append(w.params, f.Extra...)
+}
+
+// ParseCompressedVideoFormat reads the parameters of a SMPTE ST 2110-22
+// stream. Width, height, exact frame rate, TP and SSN are required.
+func ParseCompressedVideoFormat(params FormatParameters) (*CompressedVideoFormat, error) {
+	r := newFmtpReader(params)
+
+	f := &CompressedVideoFormat{
+		Width:          r.int("width", true),
+		Height:         r.int("height", true),
+		ExactFrameRate: r.frameRate("exactframerate", true),
+		TP:             r.string("TP", true),
+		SSN:            r.string("SSN", true),
+		Profile:        r.string("profile", false),
+		Level:          r.string("level", false),
+		Sublevel:       r.string("sublevel", false),
+		Sampling:       r.string("sampling", false),
+		Depth:          r.int("depth", false),
+		Colorimetry:    r.string("colorimetry", false),
+		TCS:            r.string("TCS", false),
+		Range:          r.string("RANGE", false),
+		Interlace:      r.flag("interlace"),
+		Segmented:      r.flag("segmented"),
+		TROFF:          r.int("TROFF", false),
+		CMAX:           r.int("CMAX", false),
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	f.Extra = r.extra()
+
+	return f, nil
+}
+
+func (f *CompressedVideoFormat) FormatParameters() FormatParameters {
+	w := &fmtpWriter{}
+
+	w.string("profile", f.Profile)
+	w.string("level", f.Level)
+	w.string("sublevel", f.Sublevel)
+	w.string("sampling", f.Sampling)
+	w.int("depth", f.Depth)
+	w.int("width", f.Width)
+	w.int("height", f.Height)
+	w.frameRate("exactframerate", f.ExactFrameRate)
+	w.string("TCS", f.TCS)
+	w.string("colorimetry", f.Colorimetry)
+	w.string("RANGE", f.Range)
+	w.string("SSN", f.SSN)
+	w.string("TP", f.TP)
+	w.flag("interlace", f.Interlace)
+	w.flag("segmented", f.Segmented)
+	w.int("TROFF", f.TROFF)
+	w.int("CMAX", f.CMAX)
+
+	return append(w.params, f.Extra...)
+}
+
+// ParseAncillaryFormat reads the parameters of a SMPTE ST 2110-40 stream. All
+// parameters are optional.
+func ParseAncillaryFormat(params FormatParameters) (*AncillaryFormat, error) {
+	r := newFmtpReader(params)
+	r.used["DID_SDID"] = true
+
+	f := &AncillaryFormat{
+		VPIDCode:       r.int("VPID_Code", false),
+		ExactFrameRate: r.frameRate("exactframerate", false),
+		SSN:            r.string("SSN", false),
+		TM:             r.string("TM", false),
+		TROFF:          r.int("TROFF", false),
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for _, v := range params.GetAll("DID_SDID") {
+		d, err := parseDIDSDID(v)
+		if err != nil {
+			return nil, err
+		}
+
+		f.DIDSDIDs = append(f.DIDSDIDs, d)
+	}
+
+	f.Extra = r.extra()
+
+	return f, nil
+}
+
+func (f *AncillaryFormat) FormatParameters() FormatParameters {
+	w := &fmtpWriter{}
+
+	for _, d := range f.DIDSDIDs {
+		w.string("DID_SDID", d.String())
+	}
+
+	w.int("VPID_Code", f.VPIDCode)
+	w.frameRate("exactframerate", f.ExactFrameRate)
+	w.string("SSN", f.SSN)
+	w.string("TM", f.TM)
+	w.int("TROFF", f.TROFF)
+
+	return append(w.params, f.Extra...)
+}
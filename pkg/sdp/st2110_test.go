@@ -0,0 +1,152 @@
+package sdp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testVideoSDP = "v=0\r\n" +
+	"o=- 123456 11 IN IP4 192.168.100.2\r\n" +
+	"s=Example of a SMPTE ST2110-20 signal\r\n" +
+	"t=0 0\r\n" +
+	"a=recvonly\r\n" +
+	"m=video 50000 RTP/AVP 112\r\n" +
+	"c=IN IP4 239.100.9.10/32\r\n" +
+	"a=rtpmap:112 raw/90000\r\n" +
+	"a=fmtp:112 sampling=YCbCr-4:2:2; width=1280; height=720; exactframerate=60000/1001; depth=10; TCS=SDR; colorimetry=BT709; PM=2110GPM; SSN=ST2110-20:2017; TP=2110TPN; interlace\r\n" +
+	"m=video 50020 RTP/AVP 98\r\n" +
+	"c=IN IP4 239.100.9.11/32\r\n" +
+	"a=rtpmap:98 jxsv/90000\r\n" +
+	"a=fmtp:98 profile=High444.12; level=1k-1; sublevel=Sublev3bpp; sampling=YCbCr-4:2:2; depth=10; width=1920; height=1080; exactframerate=25; TCS=SDR; colorimetry=BT709; RANGE=NARROW; SSN=ST2110-22:2019; TP=2110TPN; packetmode=0\r\n" +
+	"m=video 50010 RTP/AVP 100\r\n" +
+	"c=IN IP4 239.100.9.12/32\r\n" +
+	"a=rtpmap:100 smpte291/90000\r\n" +
+	"a=fmtp:100 DID_SDID={0x41,0x01}; DID_SDID={0x60,0x60}; VPID_Code=133\r\n"
+
+func TestParseRawVideoFormat(t *testing.T) {
+	s, err := Parse([]byte(testVideoSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	params, err := s.Media[0].FormatParameters(112)
+	if err != nil {
+		t.Fatalf("Media.FormatParameters() error = %v", err)
+	}
+
+	f, err := ParseRawVideoFormat(params)
+	if err != nil {
+		t.Fatalf("ParseRawVideoFormat() error = %v", err)
+	}
+
+	want := &RawVideoFormat{
+		Sampling:       "YCbCr-4:2:2",
+		Width:          1280,
+		Height:         720,
+		ExactFrameRate: FrameRate{Numerator: 60000, Denominator: 1001},
+		Depth:          10,
+		Colorimetry:    "BT709",
+		TCS:            "SDR",
+		PM:             "2110GPM",
+		SSN:            "ST2110-20:2017",
+		TP:             "2110TPN",
+		Interlace:      true,
+		Extra:          FormatParameters{},
+	}
+
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("ParseRawVideoFormat() = %+v, want %+v", f, want)
+	}
+
+	if a := f.FormatParameters().Attribute(112); a != s.Media[0].Attributes[1] {
+		t.Errorf("RawVideoFormat.FormatParameters().Attribute() = %+v, want %+v", a, s.Media[0].Attributes[1])
+	}
+}
+
+func TestParseCompressedVideoFormat(t *testing.T) {
+	s, err := Parse([]byte(testVideoSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	params, err := s.Media[1].FormatParameters(98)
+	if err != nil {
+		t.Fatalf("Media.FormatParameters() error = %v", err)
+	}
+
+	f, err := ParseCompressedVideoFormat(params)
+	if err != nil {
+		t.Fatalf("ParseCompressedVideoFormat() error = %v", err)
+	}
+
+	if f.Profile != "High444.12" || f.Width != 1920 || f.ExactFrameRate != (FrameRate{25, 1}) || f.Range != "NARROW" {
+		t.Errorf("ParseCompressedVideoFormat() = %+v", f)
+	}
+
+	if !reflect.DeepEqual(f.Extra, FormatParameters{{Name: "packetmode", Value: "0"}}) {
+		t.Errorf("CompressedVideoFormat.Extra = %+v, want packetmode=0", f.Extra)
+	}
+
+	if a := f.FormatParameters().Attribute(98); a != s.Media[1].Attributes[1] {
+		t.Errorf("CompressedVideoFormat.FormatParameters().Attribute() = %+v, want %+v", a, s.Media[1].Attributes[1])
+	}
+}
+
+func TestParseAncillaryFormat(t *testing.T) {
+	s, err := Parse([]byte(testVideoSDP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	params, err := s.Media[2].FormatParameters(100)
+	if err != nil {
+		t.Fatalf("Media.FormatParameters() error = %v", err)
+	}
+
+	f, err := ParseAncillaryFormat(params)
+	if err != nil {
+		t.Fatalf("ParseAncillaryFormat() error = %v", err)
+	}
+
+	wantIDs := []DIDSDID{{DID: 0x41, SDID: 0x01}, {DID: 0x60, SDID: 0x60}}
+
+	if !reflect.DeepEqual(f.DIDSDIDs, wantIDs) || f.VPIDCode != 133 {
+		t.Errorf("ParseAncillaryFormat() = %+v, want %+v and VPID code 133", f, wantIDs)
+	}
+
+	if a := f.FormatParameters().Attribute(100); a != s.Media[2].Attributes[1] {
+		t.Errorf("AncillaryFormat.FormatParameters().Attribute() = %+v, want %+v", a, s.Media[2].Attributes[1])
+	}
+}
+
+func TestParseRawVideoFormat_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fmtp    string
+		wantErr error
+	}{
+		{
+			name:    "missing width",
+			fmtp:    "112 sampling=YCbCr-4:2:2; height=720; exactframerate=50; depth=10; colorimetry=BT709; PM=2110GPM; SSN=ST2110-20:2017",
+			wantErr: ErrMissingField,
+		},
+		{
+			name:    "bad frame rate",
+			fmtp:    "112 sampling=YCbCr-4:2:2; width=1280; height=720; exactframerate=50/0; depth=10; colorimetry=BT709; PM=2110GPM; SSN=ST2110-20:2017",
+			wantErr: ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, params, err := ParseFormatParameters(tt.fmtp)
+			if err != nil {
+				t.Fatalf("ParseFormatParameters() error = %v", err)
+			}
+
+			if _, err := ParseRawVideoFormat(params); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseRawVideoFormat() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package sdp
+
+import (
+	"slices"
+	"time"
+)
+
+// Seconds between the NTP epoch (1900-01-01) and the Unix epoch
+const ntpEpochOffset = 2208988800
+
+// Bound for z= offsets when searching repetitions, which shift them by hours
+// in practice.
+const maxTimeZoneAdjustment = 24 * time.Hour
+
+// NTPTime converts an NTP timestamp in seconds as used by t=, r= and z= lines
+// to a time. Zero, which SDP uses for unbounded times, is returned as the
+// zero time.
+func NTPTime(ntp uint64) time.Time {
+	if ntp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(ntp)-ntpEpochOffset, 0)
+}
+
+// NTP converts a time to an NTP timestamp in seconds. The zero time is
+// returned as zero.
+func NTP(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return uint64(t.Unix() + ntpEpochOffset)
+}
+
+// Occurrence is one period in which a session is active. Start or End is zero
+// if the period is unbounded on that side.
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (o *Occurrence) contains(t time.Time) bool {
+	return (o.Start.IsZero() || !t.Before(o.Start)) && (o.End.IsZero() || t.Before(o.End))
+}
+
+func (o *Occurrence) overlaps(from, to time.Time) bool {
+	return (o.Start.IsZero() || !o.Start.After(to)) && (o.End.IsZero() || o.End.After(from))
+}
+
+// Start returns the earliest start time of the session, or the zero time if
+// the session has no start bound.
+func (s *Session) Start() time.Time {
+	var start time.Time
+
+	for i, t := range s.Timings {
+		if t.Start == 0 {
+			return time.Time{}
+		}
+
+		if st := NTPTime(t.Start); i == 0 || st.Before(start) {
+			start = st
+		}
+	}
+
+	return start
+}
+
+// End returns the latest stop time of the session, or the zero time if the
+// session is unbounded.
+func (s *Session) End() time.Time {
+	var end time.Time
+
+	for _, t := range s.Timings {
+		if t.Stop == 0 {
+			return time.Time{}
+		}
+
+		if st := NTPTime(t.Stop); st.After(end) {
+			end = st
+		}
+	}
+
+	return end
+}
+
+// adjust applies the time zone adjustment in effect at t (RFC 8866, section
+// 5.11). Adjustments are relative to the base time of the session, so only
+// the latest one before t counts.
+func (s *Session) adjust(t time.Time) time.Time {
+	var offset time.Duration
+
+	for _, z := range s.TimeZones {
+		if !t.Before(NTPTime(z.AdjustmentTime)) {
+			offset = z.Offset
+		}
+	}
+
+	return t.Add(offset)
+}
+
+// Occurrences returns the periods between from and to in which the session is
+// active, taking r= repeat times and z= time zone adjustments into account.
+func (s *Session) Occurrences(from, to time.Time) []Occurrence {
+	occurrences := make([]Occurrence, 0)
+
+	for _, t := range s.Timings {
+		start, stop := NTPTime(t.Start), NTPTime(t.Stop)
+
+		if len(t.Repeats) == 0 || start.IsZero() {
+			o := Occurrence{Start: start, End: stop}
+
+			if o.overlaps(from, to) {
+				occurrences = append(occurrences, o)
+			}
+
+			continue
+		}
+
+		for _, r := range t.Repeats {
+			occurrences = append(occurrences, s.repeat(start, stop, &r, from, to)...)
+		}
+	}
+
+	return occurrences
+}
+
+// RFC 8866, section 5.10:
+// Repeats start at the start time and recur every interval, with each
+// offset giving an active period of the given duration, until the stop time.
+func (s *Session) repeat(start, stop time.Time, r *Repeat, from, to time.Time) []Occurrence {
+	occurrences := make([]Occurrence, 0)
+
+	offsets := r.Offsets
+	if len(offsets) == 0 {
+		offsets = []time.Duration{0}
+	}
+
+	base := start
+
+	// Skip the repetitions that end before from, leaving room for the
+	// offsets and time zone adjustments.
+	if r.Interval > 0 {
+		span := slices.Max(offsets) + r.Duration + maxTimeZoneAdjustment
+
+		if skip := from.Sub(start) - span; skip > 0 {
+			base = base.Add(skip / r.Interval * r.Interval)
+		}
+	}
+
+	for ; !base.After(to.Add(maxTimeZoneAdjustment)) && (stop.IsZero() || base.Before(stop)); base = base.Add(r.Interval) {
+		for _, offset := range offsets {
+			begin := s.adjust(base.Add(offset))
+
+			o := Occurrence{
+				Start: begin,
+				End:   begin.Add(r.Duration),
+			}
+
+			if !stop.IsZero() && o.End.After(stop) {
+				o.End = stop
+			}
+
+			if o.Start.Before(o.End) && o.overlaps(from, to) {
+				occurrences = append(occurrences, o)
+			}
+		}
+
+		if r.Interval <= 0 {
+			break
+		}
+	}
+
+	return occurrences
+}
+
+// ActiveAt reports whether the session is scheduled to be active at t.
+func (s *Session) ActiveAt(t time.Time) bool {
+	for _, o := range s.Occurrences(t, t) {
+		if o.contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package sdp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNTPTime(t *testing.T) {
+	// The start time of the example in RFC 8866, section 5.9
+	want := time.Date(2018, 1, 8, 10, 0, 0, 0, time.UTC)
+
+	if got := NTPTime(3724394400); !got.Equal(want) {
+		t.Errorf("NTPTime() = %v, want %v", got, want)
+	}
+
+	if got := NTP(want); got != 3724394400 {
+		t.Errorf("NTP() = %d, want 3724394400", got)
+	}
+
+	if !NTPTime(0).IsZero() || NTP(time.Time{}) != 0 {
+		t.Errorf("zero is not unbounded")
+	}
+}
+
+func TestSession_ActiveAt(t *testing.T) {
+	base := NTPTime(3724394400)
+
+	s := &Session{
+		Timings: []Timing{{
+			Start: 3724394400,
+			Stop:  3724394400 + 4*7*24*3600,
+			Repeats: []Repeat{{
+				Interval: 7 * 24 * time.Hour,
+				Duration: time.Hour,
+				Offsets:  []time.Duration{0, 25 * time.Hour},
+			}},
+		}},
+		TimeZones: []TimeZone{
+			// From the third week on, the session starts an hour earlier.
+			{AdjustmentTime: 3724394400 + 14*24*3600, Offset: -time.Hour},
+		},
+	}
+
+	tests := []struct {
+		offset time.Duration
+		want   bool
+	}{
+		{-time.Minute, false},
+		{30 * time.Minute, true},
+		{2 * time.Hour, false},
+		{25*time.Hour + 10*time.Minute, true},
+		{7*24*time.Hour + 10*time.Minute, true},
+		{14*24*time.Hour - 30*time.Minute, true},
+		{14*24*time.Hour + 30*time.Minute, false},
+		{28*24*time.Hour + 10*time.Minute, false},
+	}
+	for _, tt := range tests {
+		if got := s.ActiveAt(base.Add(tt.offset)); got != tt.want {
+			t.Errorf("Session.ActiveAt(start + %v) = %v, want %v", tt.offset, got, tt.want)
+		}
+	}
+
+	if !s.Start().Equal(base) || !s.End().Equal(base.Add(28*24*time.Hour)) {
+		t.Errorf("Session bounds = %v - %v", s.Start(), s.End())
+	}
+
+	if got := len(s.Occurrences(base, base.Add(28*24*time.Hour))); got != 8 {
+		t.Errorf("len(Session.Occurrences()) = %d, want 8", got)
+	}
+}
+
+func TestSession_ActiveAt_unbounded(t *testing.T) {
+	s := &Session{Timings: []Timing{{}}}
+
+	if !s.ActiveAt(time.Now()) || !s.Start().IsZero() || !s.End().IsZero() {
+		t.Errorf("t=0 0 session is not permanent")
+	}
+}
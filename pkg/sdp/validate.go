@@ -0,0 +1,211 @@
+package sdp
+
+import (
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidSession = errors.New("invalid session description")
+
+// Packet times allowed by AES67, section 7.1, in samples at 48 kHz: 125 µs,
+// 250 µs, 333 µs, 1 ms and 4 ms. The same counts apply at 44.1 kHz, where
+// 48 samples take 1.09 ms, and are doubled at 96 kHz.
+var aes67PacketSamples = []int{6, 12, 16, 48, 192}
+
+// AES67, section 7.2: a packet must fit a standard Ethernet frame with
+// 1440 bytes left for the RTP payload.
+const aes67MaxPayloadSize = 1440
+
+func invalidf(format string, args ...interface{}) error {
+	return errors.Wrapf(ErrInvalidSession, format, args...)
+}
+
+// Validate checks the session for problems the parser tolerates or that a
+// Session built in code may have: incomplete origin or connection data,
+// missing session name or timing, media without connection data and rtpmap
+// attributes that don't match the formats of their media description.
+func (s *Session) Validate() error {
+	if s.Version != 0 {
+		return ErrUnsupportedVersion
+	}
+
+	o := s.Origin
+
+	if o.Username == "" || o.SessionID == "" || o.NetworkType == "" || o.AddressType == "" || o.Address == "" {
+		return invalidf("incomplete origin")
+	}
+
+	if err := validateAddress(o.NetworkType, o.AddressType, o.Address); err != nil {
+		return invalidf("origin: %v", err)
+	}
+
+	if s.Name == "" {
+		return invalidf("missing session name")
+	}
+
+	if len(s.Timings) == 0 {
+		return invalidf("missing timing")
+	}
+
+	if s.Connection != nil {
+		if err := validateAddress(s.Connection.NetworkType, s.Connection.AddressType, s.Connection.Address); err != nil {
+			return invalidf("connection: %v", err)
+		}
+	}
+
+	for i := range s.Media {
+		if err := s.validateMedia(&s.Media[i]); err != nil {
+			return invalidf("media %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+func validateAddress(networkType, addressType, address string) error {
+	if networkType != NetworkTypeInternet {
+		return nil
+	}
+
+	ip := net.ParseIP(address)
+
+	switch addressType {
+	case AddressTypeIPv4:
+		if ip != nil && ip.To4() == nil {
+			return errors.Errorf("%s is not an IPv4 address", address)
+		}
+	case AddressTypeIPv6:
+		if ip != nil && ip.To4() != nil {
+			return errors.Errorf("%s is not an IPv6 address", address)
+		}
+	default:
+		return errors.Errorf("unknown address type %q", addressType)
+	}
+
+	return nil
+}
+
+func (s *Session) validateMedia(m *Media) error {
+	if m.Type == "" || m.Protocol == "" || len(m.Formats) == 0 {
+		return errors.New("incomplete media description")
+	}
+
+	// RFC 8866, section 5.7:
+	// Connection data is required at session level or in every media
+	// description.
+	if s.connection(m) == nil {
+		return errors.New("missing connection data")
+	}
+
+	for _, c := range m.Connections {
+		if err := validateAddress(c.NetworkType, c.AddressType, c.Address); err != nil {
+			return errors.Wrap(err, "connection")
+		}
+	}
+
+	if !strings.HasPrefix(m.Protocol, "RTP/") {
+		return nil
+	}
+
+	mapped := make(map[string]bool)
+
+	for _, v := range m.Attributes.GetAll("rtpmap") {
+		pt, _, _ := strings.Cut(v, " ")
+
+		if !slices.Contains(m.Formats, pt) {
+			return errors.Errorf("rtpmap for payload type %s not listed in m=", pt)
+		}
+
+		mapped[pt] = true
+	}
+
+	// RFC 3551, section 3:
+	// Dynamic payload types have no meaning without an rtpmap.
+	for _, f := range m.Formats {
+		if pt, err := strconv.Atoi(f); err == nil && pt >= 96 && pt <= 127 && !mapped[f] {
+			return errors.Errorf("missing rtpmap for dynamic payload type %d", pt)
+		}
+	}
+
+	return nil
+}
+
+// ValidateAES67 checks the audio streams of the session against the
+// constraints of AES67: L16 or L24 encoding at 44.1, 48 or 96 kHz, one of the
+// standard packet times, packets that fit the payload size limit, and a
+// reference and media clock.
+func (s *Session) ValidateAES67() error {
+	for i := range s.Media {
+		m := &s.Media[i]
+
+		if m.Type != "audio" {
+			continue
+		}
+
+		if err := s.validateAES67(m); err != nil {
+			return invalidf("media %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Session) validateAES67(m *Media) error {
+	as, err := s.AudioStream(m)
+	if err != nil {
+		return err
+	}
+
+	bytesPerSample := 0
+
+	switch as.Encoding {
+	case "L16":
+		bytesPerSample = 2
+	case "L24":
+		bytesPerSample = 3
+	default:
+		return errors.Errorf("encoding %s not allowed by AES67", as.Encoding)
+	}
+
+	switch as.SampleRate {
+	case 44100, 48000, 96000:
+	default:
+		return errors.Errorf("sample rate %d not allowed by AES67", as.SampleRate)
+	}
+
+	// Packet times are rounded in the ptime attribute, so the sample count
+	// is rounded as well.
+	samples := int(math.Round(float64(as.SampleRate) * as.PacketTime.Seconds()))
+
+	scale := 1
+	if as.SampleRate == 96000 {
+		scale = 2
+	}
+
+	if samples%scale != 0 || !slices.Contains(aes67PacketSamples, samples/scale) {
+		return errors.Errorf("packet time %v not allowed by AES67", as.PacketTime)
+	}
+
+	if size := samples * as.Channels * bytesPerSample; size > aes67MaxPayloadSize {
+		return errors.Errorf("payload of %d bytes exceeds %d bytes", size, aes67MaxPayloadSize)
+	}
+
+	if as.Destination == nil {
+		return errors.New("connection address is not an IP address")
+	}
+
+	if as.RefClock.Source == "" {
+		return errors.New("missing ts-refclk")
+	}
+
+	if _, ok := s.attribute(m, "mediaclk"); !ok {
+		return errors.New("missing mediaclk")
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package sdp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSession_Validate(t *testing.T) {
+	const valid = "v=0\r\n" +
+		"o=- 1 1 IN IP4 192.0.2.1\r\n" +
+		"s=x\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 5004 RTP/AVP 0 98\r\n" +
+		"c=IN IP4 239.0.0.1/32\r\n" +
+		"a=rtpmap:98 L24/48000/2\r\n"
+
+	tests := []struct {
+		name    string
+		sdp     string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			sdp:  valid,
+		},
+		{
+			name: "fixture",
+			sdp:  testSDP,
+		},
+		{
+			name:    "origin address family",
+			sdp:     strings.Replace(valid, "IP4 192.0.2.1", "IP4 2001:db8::1", 1),
+			wantErr: true,
+		},
+		{
+			name:    "missing connection",
+			sdp:     strings.Replace(valid, "c=IN IP4 239.0.0.1/32\r\n", "", 1),
+			wantErr: true,
+		},
+		{
+			name:    "rtpmap for unlisted payload type",
+			sdp:     valid + "a=rtpmap:99 L16/48000/2\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing rtpmap",
+			sdp:     strings.Replace(valid, "a=rtpmap:98 L24/48000/2\r\n", "", 1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(tt.sdp))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			err = s.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Session.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidSession) {
+				t.Errorf("Session.Validate() error = %v, want %v", err, ErrInvalidSession)
+			}
+		})
+	}
+
+	if err := (&Session{}).Validate(); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Session.Validate() of empty session error = %v, want %v", err, ErrInvalidSession)
+	}
+}
+
+func TestSession_ValidateAES67(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		wantErr bool
+	}{
+		{
+			name: "fixture",
+		},
+		{
+			name:    "encoding",
+			old:     "L24/48000/8",
+			new:     "L8/48000/8",
+			wantErr: true,
+		},
+		{
+			name:    "sample rate",
+			old:     "L24/48000/8",
+			new:     "L24/32000/8",
+			wantErr: true,
+		},
+		{
+			name:    "packet time",
+			old:     "a=ptime:0.125",
+			new:     "a=ptime:2",
+			wantErr: true,
+		},
+		{
+			name:    "payload size",
+			old:     "a=ptime:0.125",
+			new:     "a=ptime:4",
+			wantErr: true,
+		},
+		{
+			name:    "reference clock",
+			old:     "a=ts-refclk:ptp=IEEE1588-2008:C8-0D-32-FF-FE-4C-85-42:0\r\n",
+			new:     "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(strings.Replace(testSDP, tt.old, tt.new, 1)))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			err = s.ValidateAES67()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Session.ValidateAES67() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSession_ValidateAES67_packetTime(t *testing.T) {
+	tests := []struct {
+		rate    string
+		ptime   string
+		wantErr bool
+	}{
+		{"48000", "0.125", false},
+		{"48000", "0.333", false},
+		{"48000", "1", false},
+		{"48000", "2", true},
+		{"44100", "0.136", false},
+		{"44100", "1.09", false},
+		{"44100", "1", true},
+		{"96000", "0.125", false},
+		{"96000", "0.25", false},
+		{"96000", "0.333", false},
+		{"96000", "0.5", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rate+"/"+tt.ptime, func(t *testing.T) {
+			r := strings.NewReplacer("L24/48000/8", "L24/"+tt.rate+"/8", "a=ptime:0.125", "a=ptime:"+tt.ptime)
+
+			s, err := Parse([]byte(r.Replace(testSDP)))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			err = s.ValidateAES67()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Session.ValidateAES67() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}